@@ -2,12 +2,14 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
 	"time"
 
+	"simpletrading/dataservice/internal/domain"
 	"simpletrading/dataservice/internal/usecase"
 )
 
@@ -23,6 +25,8 @@ func (h *Handler) Router() http.Handler {
 	mux := http.NewServeMux()
 	mux.Handle("/data", JWTMiddleware(http.HandlerFunc(h.GetData)))
 	mux.Handle("/data/lowest", JWTMiddleware(http.HandlerFunc(h.GetLowestPrice)))
+	mux.Handle("/data/activity", JWTMiddleware(http.HandlerFunc(h.GetPriceActivity)))
+	mux.Handle("/data/stream", JWTMiddleware(http.HandlerFunc(h.StreamData)))
 	return mux
 }
 
@@ -73,16 +77,125 @@ func (h *Handler) StartDataGeneration() {
 	}()
 }
 
-// GetLowestPrice returns the lowest price in the last 24 hours
+// GetLowestPrice returns the lowest price in the last 24 hours, along with
+// the highest and the most recent price of the same window
 func (h *Handler) GetLowestPrice(w http.ResponseWriter, r *http.Request) {
 	// Filter data for the last 24 hours
-	lowest, err := h.uc.GetLowestPriceInLast24Hours()
+	stats, err := h.uc.GetPriceStatsInLast24Hours()
 	if err != nil {
 		http.Error(w, "Error fetching lowest price: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Return the lowest price as a JSON response
+	// Return the prices as a JSON response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]float64{"lowest": lowest})
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetPriceActivity handles the GET /data/activity endpoint. It returns the
+// price activity by time of day over the last `days` days (default 7) in
+// buckets of `bucket` (default 15m)
+func (h *Handler) GetPriceActivity(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if queryDays := r.URL.Query().Get("days"); queryDays != "" {
+		parsed, err := strconv.Atoi(queryDays)
+		if err != nil || parsed < 1 || parsed > 30 {
+			http.Error(w, "Invalid days, must be between 1 and 30", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+	bucket := 15 * time.Minute
+	if queryBucket := r.URL.Query().Get("bucket"); queryBucket != "" {
+		parsed, err := time.ParseDuration(queryBucket)
+		if err != nil || parsed < time.Minute || parsed > 24*time.Hour {
+			http.Error(w, "Invalid bucket, must be between 1m and 24h", http.StatusBadRequest)
+			return
+		}
+		bucket = parsed
+	}
+
+	activity, err := h.uc.GetPriceActivity(days, bucket)
+	if err != nil {
+		http.Error(w, "Error fetching price activity: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(activity)
+}
+
+// StreamData pushes new data points as server-sent events. Clients resume
+// with the Last-Event-ID header (or the last_id query parameter); the data
+// points of the last 24 hours after that ID are replayed first, or all of
+// them for a fresh connection.
+func (h *Handler) StreamData(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_id")
+	}
+	var after uint
+	if lastID != "" {
+		parsed, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid last event id", http.StatusBadRequest)
+			return
+		}
+		after = uint(parsed)
+	}
+
+	// Subscribe before replaying so no point falls in between
+	points, unsubscribe := h.uc.Subscribe()
+	defer unsubscribe()
+
+	replay, err := h.uc.GetDataAfter(after)
+	if err != nil {
+		http.Error(w, "Failed to get data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for _, dp := range replay {
+		writeEvent(w, dp)
+		after = dp.ID
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case dp, ok := <-points:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and replays
+				return
+			}
+			if dp.ID <= after {
+				continue
+			}
+			writeEvent(w, dp)
+			after = dp.ID
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, dp domain.DataPoint) {
+	data, _ := json.Marshal(dp)
+	fmt.Fprintf(w, "id: %d\nevent: datapoint\ndata: %s\n\n", dp.ID, data)
 }
@@ -7,3 +7,16 @@ type DataPoint struct {
 	Value     float64   `gorm:"not null"`
 	Timestamp time.Time `gorm:"autoCreateTime"`
 }
+
+// PriceStats summarizes the data points of a time window.
+type PriceStats struct {
+	Lowest  float64 `json:"lowest"`
+	Highest float64 `json:"highest"`
+	Last    float64 `json:"last"`
+}
+
+// PriceActivity measures how much the price moves at each time of day.
+type PriceActivity struct {
+	Bucket   string          `json:"bucket"`   // Width of the buckets, e.g. "15m0s"
+	Activity map[int]float64 `json:"activity"` // Summed relative price changes per bucket; bucket 0 starts at midnight UTC
+}
@@ -15,9 +15,10 @@ func NewDataRepo(db *gorm.DB) *DataRepository {
 	return &DataRepository{db: db}
 }
 
-func (r *DataRepository) Insert(value float64) error {
+func (r *DataRepository) Insert(value float64) (domain.DataPoint, error) {
 	dp := domain.DataPoint{Value: value, Timestamp: time.Now().UTC()}
-	return r.db.Create(&dp).Error
+	err := r.db.Create(&dp).Error
+	return dp, err
 }
 
 func (r *DataRepository) GetRecent(limit int) ([]domain.DataPoint, error) {
@@ -31,3 +32,11 @@ func (r *DataRepository) GetDataSince(startTime time.Time) ([]domain.DataPoint,
 	err := r.db.Where("timestamp >= ?", startTime).Order("timestamp desc").Find(&data).Error
 	return data, err
 }
+
+// GetAfterID returns the data points newer than the given ID and not older
+// than startTime, oldest first.
+func (r *DataRepository) GetAfterID(id uint, startTime time.Time) ([]domain.DataPoint, error) {
+	var data []domain.DataPoint
+	err := r.db.Where("id > ? AND timestamp >= ?", id, startTime).Order("id asc").Find(&data).Error
+	return data, err
+}
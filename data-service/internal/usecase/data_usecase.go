@@ -3,32 +3,85 @@ package usecase
 import (
 	"errors"
 	"log"
+	"math"
 	"simpletrading/dataservice/internal/domain"
 	repository "simpletrading/dataservice/internal/repository/memory"
+	"sync"
 	"time"
 )
 
 // DataUsecase provides methods for working with data
 type DataUsecase struct {
 	repo repository.DataRepository
+
+	mu          sync.Mutex
+	subscribers map[chan domain.DataPoint]struct{}
 }
 
 // NewDataUsecase initializes a new instance of DataUsecase
 func NewDataUsecase(repo repository.DataRepository) *DataUsecase {
-	return &DataUsecase{repo: repo}
+	return &DataUsecase{repo: repo, subscribers: make(map[chan domain.DataPoint]struct{})}
 }
 
-// GenerateData generates a new data point and stores it
+// GenerateData generates a new data point, stores it and publishes it to
+// the subscribers
 func (uc *DataUsecase) GenerateData(value float64) error {
-	err := uc.repo.Insert(value)
+	dp, err := uc.repo.Insert(value)
 	if err != nil {
 		log.Println("Error saving data:", err)
 		return err
 	}
 	log.Println("Generated and saved new data point:", value)
+
+	uc.publish(dp)
 	return nil
 }
 
+// Subscribe returns a channel receiving every new data point, and a function
+// to stop the subscription. A subscriber too slow to keep up has its channel
+// closed rather than block data generation; it can reconnect and catch up
+// with GetDataAfter.
+func (uc *DataUsecase) Subscribe() (<-chan domain.DataPoint, func()) {
+	ch := make(chan domain.DataPoint, 16)
+
+	uc.mu.Lock()
+	uc.subscribers[ch] = struct{}{}
+	uc.mu.Unlock()
+
+	return ch, func() {
+		uc.mu.Lock()
+		delete(uc.subscribers, ch)
+		uc.mu.Unlock()
+	}
+}
+
+// GetDataAfter returns the data points of the last 24 hours newer than the
+// given ID, oldest first
+func (uc *DataUsecase) GetDataAfter(id uint) ([]domain.DataPoint, error) {
+	oneDayAgo := time.Now().UTC().Add(-24 * time.Hour)
+	data, err := uc.repo.GetAfterID(id, oneDayAgo)
+	if err != nil {
+		log.Println("Error fetching data:", err)
+		return nil, err
+	}
+	return data, nil
+}
+
+func (uc *DataUsecase) publish(dp domain.DataPoint) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	for ch := range uc.subscribers {
+		select {
+		case ch <- dp:
+		default:
+			log.Println("Dropping slow subscriber at data point:", dp.ID)
+			delete(uc.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
 // Get retrieves the most recent data points
 
 func (uc *DataUsecase) GetRecentData(limit int) ([]domain.DataPoint, error) {
@@ -63,3 +116,53 @@ func (uc *DataUsecase) GetLowestPriceInLast24Hours() (float64, error) {
 
 	return lowest, nil
 }
+
+// GetPriceStatsInLast24Hours returns the lowest, highest and most recent
+// price of the last 24 hours.
+func (uc *DataUsecase) GetPriceStatsInLast24Hours() (domain.PriceStats, error) {
+	oneDayAgo := time.Now().UTC().Add(-24 * time.Hour)
+	data, err := uc.repo.GetDataSince(oneDayAgo)
+	if err != nil {
+		log.Println("Error fetching data:", err)
+		return domain.PriceStats{}, err
+	}
+
+	if len(data) == 0 {
+		return domain.PriceStats{}, errors.New("no data in last 24 hours")
+	}
+
+	// Data comes newest first
+	stats := domain.PriceStats{Lowest: data[0].Value, Highest: data[0].Value, Last: data[0].Value}
+	for _, dp := range data[1:] {
+		stats.Lowest = min(stats.Lowest, dp.Value)
+		stats.Highest = max(stats.Highest, dp.Value)
+	}
+
+	return stats, nil
+}
+
+// GetPriceActivity sums the relative price changes of the last given number
+// of days by time of day (UTC), in buckets of the given width. Buckets the
+// price moved most in are the busiest times of the day.
+func (uc *DataUsecase) GetPriceActivity(days int, bucket time.Duration) (domain.PriceActivity, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	data, err := uc.repo.GetDataSince(since)
+	if err != nil {
+		log.Println("Error fetching data:", err)
+		return domain.PriceActivity{}, err
+	}
+
+	// Data comes newest first; each change counts for the time it happened
+	activity := make(map[int]float64)
+	for i := 0; i+1 < len(data); i++ {
+		dp, prev := data[i], data[i+1]
+		if prev.Value <= 0 {
+			continue
+		}
+		at := dp.Timestamp.UTC()
+		sinceMidnight := at.Sub(time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC))
+		activity[int(sinceMidnight/bucket)] += math.Abs(dp.Value-prev.Value) / prev.Value
+	}
+
+	return domain.PriceActivity{Bucket: bucket.String(), Activity: activity}, nil
+}
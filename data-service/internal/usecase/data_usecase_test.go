@@ -65,3 +65,60 @@ func TestGetLowestPriceInLast24Hours(t *testing.T) {
 		t.Errorf("expected lowest to be 870, got %v", lowest)
 	}
 }
+
+func TestGetPriceStatsInLast24Hours(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewDataRepo(db)
+	uc := NewDataUsecase(*repo)
+	now := time.Now().UTC()
+
+	dummies := []domain.DataPoint{
+		{Value: 1200, Timestamp: now.Add(-2 * time.Hour)},
+		{Value: 950, Timestamp: now.Add(-1 * time.Hour)},
+		{Value: 870, Timestamp: now.Add(-5 * time.Hour)},
+		{Value: 5000, Timestamp: now.Add(-25 * time.Hour)}, // too old
+	}
+
+	for _, dp := range dummies {
+		err := db.Create(&dp).Error
+		if err != nil {
+			t.Fatalf("failed to insert dummy data: %v", err)
+		}
+	}
+
+	stats, err := uc.GetPriceStatsInLast24Hours()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Lowest != 870 || stats.Highest != 1200 || stats.Last != 950 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestGetPriceActivity(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewDataRepo(db)
+	uc := NewDataUsecase(*repo)
+	yesterday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+
+	dummies := []domain.DataPoint{
+		{Value: 100, Timestamp: yesterday.Add(9 * time.Hour)},
+		{Value: 110, Timestamp: yesterday.Add(9*time.Hour + 5*time.Minute)}, // +10% at 09:05
+		{Value: 99, Timestamp: yesterday.Add(9*time.Hour + 10*time.Minute)}, // -10% at 09:10
+		{Value: 99, Timestamp: yesterday.Add(14 * time.Hour)},               // no change at 14:00
+		{Value: 500, Timestamp: yesterday.AddDate(0, 0, -10)},               // too old
+	}
+	for _, dp := range dummies {
+		if err := db.Create(&dp).Error; err != nil {
+			t.Fatalf("failed to insert dummy data: %v", err)
+		}
+	}
+
+	activity, err := uc.GetPriceActivity(7, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(activity.Activity) != 2 || activity.Activity[14] != 0 || activity.Activity[9] < 0.199 || activity.Activity[9] > 0.201 {
+		t.Errorf("expected 20%% of moves at 9:00 and none at 14:00, got %+v", activity)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"simpletrading/tradeservice/internal/backtest"
+	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/risk"
+)
+
+// runBacktest replays Data Service history through a strategy and prints
+// the report as JSON:
+//
+//	go run ./cmd backtest -db ../data-service/data.db -strategy sma_cross -fast 10 -slow 50
+func runBacktest(args []string) error {
+	cfg := config.Load()
+
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	dbPath := flags.String("db", "", "Data Service SQLite database to read data points from")
+	csvPath := flags.String("csv", "", "CSV export of data points (timestamp,value) to read instead")
+	from := flags.String("from", "", "Replay data points from this time (RFC3339)")
+	to := flags.String("to", "", "Replay data points before this time (RFC3339)")
+	strategyName := flags.String("strategy", "buy_and_hold", "Strategy: buy_and_hold or sma_cross")
+	symbol := flags.String("symbol", "BTCUSD", "Symbol the strategy trades")
+	quantity := flags.Float64("quantity", 1, "Order size")
+	fast := flags.Int("fast", 10, "Ticks in the fast moving average (sma_cross)")
+	slow := flags.Int("slow", 50, "Ticks in the slow moving average (sma_cross)")
+	cash := flags.Float64("cash", 10000, "Initial cash")
+	rulesPath := flags.String("rules", cfg.RiskRules, "Risk rules JSON file, empty for the default rules")
+	feesPath := flags.String("fees", cfg.FeeSchedule, "Fee schedule JSON file, empty for no fees")
+	out := flags.String("out", "", "Write the report to this file instead of stdout")
+	flags.Parse(args)
+
+	start, err := parseTime(*from)
+	if err != nil {
+		return err
+	}
+	end, err := parseTime(*to)
+	if err != nil {
+		return err
+	}
+
+	var points []domain.DataPoint
+	switch {
+	case *csvPath != "":
+		f, err := os.Open(*csvPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		points, err = backtest.LoadCSV(f)
+		if err != nil {
+			return err
+		}
+		points = between(points, start, end)
+	case *dbPath != "":
+		if points, err = backtest.LoadDB(*dbPath, start, end); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("either -db or -csv is required")
+	}
+
+	strategy, err := backtest.NewStrategy(*strategyName, backtest.Params{
+		Symbol: *symbol, Quantity: *quantity, Fast: *fast, Slow: *slow,
+	})
+	if err != nil {
+		return err
+	}
+	rules, err := risk.NewEngine(*rulesPath)
+	if err != nil {
+		return err
+	}
+	feeEngine, err := fees.NewEngine(*feesPath)
+	if err != nil {
+		return err
+	}
+
+	report, err := backtest.Run(points, strategy, backtest.Config{
+		Strategy:    *strategyName,
+		Symbol:      *symbol,
+		InitialCash: *cash,
+		Rules:       rules,
+		Fees:        feeEngine,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// between keeps the points from the start time up to the end time, each
+// optional.
+func between(points []domain.DataPoint, start, end time.Time) []domain.DataPoint {
+	kept := points[:0]
+	for _, p := range points {
+		if (start.IsZero() || !p.Timestamp.Before(start)) && (end.IsZero() || p.Timestamp.Before(end)) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// parseTime parses an optional RFC3339 time; empty is the zero time.
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: must be RFC3339", v)
+	}
+	return t, nil
+}
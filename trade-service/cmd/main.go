@@ -1,22 +1,164 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"simpletrading/tradeservice/internal/auth"
+	"simpletrading/tradeservice/internal/bots"
+	"simpletrading/tradeservice/internal/calendar"
 	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/dataclient"
 	apphttp "simpletrading/tradeservice/internal/delivery/http"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/matching"
+	"simpletrading/tradeservice/internal/pricefeed"
 	"simpletrading/tradeservice/internal/repository/memory"
+	"simpletrading/tradeservice/internal/risk"
 	"simpletrading/tradeservice/internal/usecase"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		if err := runBacktest(os.Args[2:]); err != nil {
+			log.Fatalf("Backtest failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		if err := runReport(os.Args[2:]); err != nil {
+			log.Fatalf("Report failed: %v", err)
+		}
+		return
+	}
 
 	db, cfg := config.Init()
 
 	repo := memory.NewTradeRepository(db)
-	uc := usecase.NewTradeUsecase(repo, cfg)
-	handler := apphttp.NewHandler(uc)
+
+	if err := repo.RebuildPositions(); err != nil {
+		log.Fatalf("Failed to rebuild positions: %v", err)
+	}
+
+	// Rebuild the order books from the orders still resting in the database
+	engine := matching.NewEngineWithPolicy(cfg.SelfMatchPolicy)
+	open, err := repo.ListOpen()
+	if err != nil {
+		log.Fatalf("Failed to load open orders: %v", err)
+	}
+	engine.Load(open)
+
+	rules, err := risk.NewEngine(cfg.RiskRules)
+	if err != nil {
+		log.Fatalf("Failed to load risk rules: %v", err)
+	}
+	feeEngine, err := fees.NewEngine(cfg.FeeSchedule)
+	if err != nil {
+		log.Fatalf("Failed to load fee schedule: %v", err)
+	}
+	sessions, err := calendar.NewEngine(cfg.Calendar)
+	if err != nil {
+		log.Fatalf("Failed to load trading calendar: %v", err)
+	}
+
+	// Reload the risk rules, fee schedule and trading calendar on SIGHUP so
+	// limits, fees and sessions change without a restart
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := rules.Reload(); err != nil {
+				log.Println("Failed to reload risk rules:", err)
+			} else {
+				log.Println("Risk rules reloaded")
+			}
+			if err := feeEngine.Reload(); err != nil {
+				log.Println("Failed to reload fee schedule:", err)
+			} else {
+				log.Println("Fee schedule reloaded")
+			}
+			if err := sessions.Reload(); err != nil {
+				log.Println("Failed to reload trading calendar:", err)
+			} else {
+				log.Println("Trading calendar reloaded")
+			}
+		}
+	}()
+
+	tokens := auth.NewTokenSource(cfg.AuthUrl, cfg.ClientId, cfg.ClientSecret)
+	prices := dataclient.New(cfg.DataUrl, tokens, dataclient.Options{
+		ActivityURL:      cfg.DataActivityUrl,
+		Timeout:          cfg.DataTimeout,
+		Retries:          cfg.DataRetries,
+		BreakerThreshold: cfg.DataBreakerThreshold,
+		BreakerCooldown:  cfg.DataBreakerCooldown,
+		Fallback:         cfg.DataFallback,
+		MaxStaleness:     cfg.DataMaxStaleness,
+	})
+
+	// Keep the 24h prices in memory from the data service stream when one is
+	// configured, falling back to polling while the stream is down
+	var source usecase.PriceSource = prices
+	var ticks bots.Ticks
+	if cfg.DataStreamUrl != "" {
+		feed := pricefeed.NewFeed(cfg.DataStreamUrl, tokens, prices, cfg.DataMaxStaleness)
+		go feed.Run(context.Background())
+		source = feed
+		ticks = feed
+	}
+
+	// Halts are kept in the live database and stop paper trading too
+	halts := usecase.NewHaltUsecase(memory.NewHaltRepository(db), cfg)
+	if cfg.VolatilityHaltPercent > 0 {
+		go halts.RunVolatilityHalts(context.Background(), source)
+	}
+
+	uc := usecase.NewTradeUsecase(repo, engine, rules, feeEngine, sessions, halts, source, cfg)
+
+	// Paper trading keeps its own book and ledger in a separate database,
+	// sharing the rules, fees, calendar, halts and prices of live trading
+	paperDB := config.InitDatabase(cfg.PaperDBPath)
+	paperRepo := memory.NewTradeRepository(paperDB)
+	if err := paperRepo.RebuildPositions(); err != nil {
+		log.Fatalf("Failed to rebuild paper positions: %v", err)
+	}
+	paperEngine := matching.NewEngineWithPolicy(cfg.SelfMatchPolicy)
+	paperOpen, err := paperRepo.ListOpen()
+	if err != nil {
+		log.Fatalf("Failed to load open paper orders: %v", err)
+	}
+	paperEngine.Load(paperOpen)
+	paper := usecase.NewPaperTradeUsecase(paperRepo, paperEngine, rules, feeEngine, sessions, halts, source, cfg)
+
+	// Send the slices of TWAP and VWAP orders as they fall due
+	go uc.RunAlgoOrders(context.Background())
+	go paper.RunAlgoOrders(context.Background())
+
+	// Release stop, take profit and trailing stop orders as the price moves
+	go uc.RunTriggers(context.Background())
+	go paper.RunTriggers(context.Background())
+
+	// Expire DAY orders at the session close and release the orders queued
+	// while the market was closed
+	go uc.RunSessions(context.Background())
+	go paper.RunSessions(context.Background())
+
+	// Deliver the trade events to the webhooks. Webhooks get live trading
+	// only; the paper outbox is just pruned
+	outbox := usecase.NewOutboxUsecase(memory.NewOutboxRepository(db), cfg)
+	go outbox.Run(context.Background())
+	go usecase.NewOutboxUsecase(memory.NewOutboxRepository(paperDB), cfg).Run(context.Background())
+
+	idem := usecase.NewIdempotencyUsecase(memory.NewIdempotencyRepository(db), cfg.IdempotencyTTL)
+	runner := bots.NewRunner(uc, paper, source, ticks)
+	if err := runner.CancelLeftoverOrders(); err != nil {
+		log.Fatalf("Failed to cancel leftover bot orders: %v", err)
+	}
+	handler := apphttp.NewHandler(uc, paper, idem, halts, usecase.NewReportUsecase(repo), outbox, runner)
 
 	log.Println("Trade Service running on", cfg.Port)
 	http.ListenAndServe(cfg.Port, handler.Router())
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+// runReport prints the end-of-day statements of a day from the trade
+// database as JSON or CSV:
+//
+//	go run ./cmd report -date 2026-01-31 -format csv -out daily.csv
+func runReport(args []string) error {
+	cfg := config.Load()
+
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	dbPath := flags.String("db", cfg.DBPath, "Trade Service SQLite database")
+	date := flags.String("date", time.Now().UTC().Format(time.DateOnly), "UTC day to report (YYYY-MM-DD)")
+	user := flags.String("user", "", "Only this user's statement, without the firm-wide summary")
+	format := flags.String("format", "json", "Output format: json or csv")
+	out := flags.String("out", "", "Write the report to this file instead of stdout")
+	flags.Parse(args)
+
+	day, err := time.Parse(time.DateOnly, *date)
+	if err != nil {
+		return fmt.Errorf("invalid date %q: must be YYYY-MM-DD", *date)
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("invalid format %q: must be json or csv", *format)
+	}
+
+	reports := usecase.NewReportUsecase(memory.NewTradeRepository(config.InitDatabase(*dbPath)))
+	report, err := reports.DailyReport(day, *user)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+	if *format == "csv" {
+		return usecase.WriteDailyReportCSV(w, report)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
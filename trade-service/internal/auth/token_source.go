@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// refreshBefore is how long before its expiry a token is replaced
+	refreshBefore = time.Minute
+	// defaultLifetime is assumed for tokens without an exp claim
+	defaultLifetime = 5 * time.Minute
+	// fetchTimeout bounds a token request, so a hung Auth Service cannot
+	// hold up the callers sharing it forever
+	fetchTimeout = 10 * time.Second
+)
+
+var tokenClient = &http.Client{Timeout: fetchTimeout}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// TokenSource hands out the machine token for calls to other services. The
+// token is cached until shortly before it expires, and concurrent callers
+// share a single request to the Auth Service.
+type TokenSource struct {
+	authURL      string
+	clientID     string
+	clientSecret string
+
+	mu       sync.Mutex
+	token    string
+	expiry   time.Time
+	inflight *fetch
+}
+
+// fetch is a token request that callers can wait on.
+type fetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func NewTokenSource(authURL, clientID, clientSecret string) *TokenSource {
+	return &TokenSource{authURL: authURL, clientID: clientID, clientSecret: clientSecret}
+}
+
+// Token returns a valid machine token, fetching a new one if the cached token
+// is missing or about to expire. It gives up when ctx is done; the fetch
+// goes on for the other callers.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	if s.token != "" && time.Now().Add(refreshBefore).Before(s.expiry) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+
+	f := s.inflight
+	if f == nil {
+		f = &fetch{done: make(chan struct{})}
+		s.inflight = f
+		go s.refresh(f)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-f.done:
+		return f.token, f.err
+	}
+}
+
+// Invalidate drops the cached token if it is still the given one, e.g. after
+// another service answered 401 to it. The next Token call fetches a new one.
+func (s *TokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = ""
+		s.expiry = time.Time{}
+	}
+}
+
+// refresh fetches a token for every caller waiting on f, so it does not
+// follow any one caller's context.
+func (s *TokenSource) refresh(f *fetch) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	f.token, f.err = GetMachineToken(ctx, s.authURL, s.clientID, s.clientSecret)
+
+	s.mu.Lock()
+	if f.err == nil {
+		s.token = f.token
+		s.expiry = tokenExpiry(f.token)
+	}
+	s.inflight = nil
+	s.mu.Unlock()
+
+	close(f.done)
+}
+
+// tokenExpiry reads the exp claim without verifying the signature; the token
+// is only passed on, never trusted here.
+func tokenExpiry(token string) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err == nil {
+		if exp, ok := claims["exp"].(float64); ok {
+			return time.Unix(int64(exp), 0)
+		}
+	}
+	return time.Now().Add(defaultLifetime)
+}
+
+func GetMachineToken(ctx context.Context, authURL, clientID, clientSecret string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", authURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.SetBasicAuth(clientID, clientSecret)
+
+	resp, err := tokenClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get token: %s", resp.Status)
+	}
+
+	var tokenRes TokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&tokenRes)
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Received new machine token")
+
+	return tokenRes.AccessToken, nil
+}
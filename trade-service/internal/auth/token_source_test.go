@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// setupAuthServer issues tokens that expire after the given lifetime and
+// counts how many were requested.
+func setupAuthServer(t *testing.T, lifetime time.Duration) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		// Slow enough for concurrent callers to pile up
+		time.Sleep(20 * time.Millisecond)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": "myclientid",
+			"n":   n,
+			"exp": time.Now().Add(lifetime).Unix(),
+		})
+		signed, _ := token.SignedString([]byte("mysecret"))
+		json.NewEncoder(w).Encode(map[string]string{"access_token": signed})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestTokenIsCachedAndShared(t *testing.T) {
+	server, calls := setupAuthServer(t, time.Hour)
+	source := NewTokenSource(server.URL, "myclientid", "myclientsecret")
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token, err := source.Token(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			tokens[i] = token
+		}(i)
+	}
+	wg.Wait()
+
+	if _, err := source.Token(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("expected a single token request, got %d", n)
+	}
+	for _, token := range tokens[1:] {
+		if token != tokens[0] {
+			t.Fatal("expected all callers to share the same token")
+		}
+	}
+}
+
+func TestTokenRefreshedBeforeExpiry(t *testing.T) {
+	// Tokens expiring within the refresh window are never reused
+	server, calls := setupAuthServer(t, refreshBefore/2)
+	source := NewTokenSource(server.URL, "myclientid", "myclientsecret")
+
+	source.Token(context.Background())
+	source.Token(context.Background())
+
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("expected a new token per call, got %d requests", n)
+	}
+}
+
+func TestInvalidateFetchesNewToken(t *testing.T) {
+	server, calls := setupAuthServer(t, time.Hour)
+	source := NewTokenSource(server.URL, "myclientid", "myclientsecret")
+
+	first, _ := source.Token(context.Background())
+	source.Invalidate("some-older-token")
+	if again, _ := source.Token(context.Background()); again != first {
+		t.Error("expected invalidating a different token to keep the cache")
+	}
+
+	source.Invalidate(first)
+	second, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second == first || atomic.LoadInt32(calls) != 2 {
+		t.Error("expected a fresh token after invalidation")
+	}
+}
@@ -0,0 +1,372 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/risk"
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+const (
+	userID = "backtest"
+	window = 24 * time.Hour
+
+	feeVolumeWindow = 30 * 24 * time.Hour
+	maxCurvePoints  = 1000
+	epsilon         = 1e-9
+)
+
+// Config sets up a backtest. Rules and Fees are the engines the live
+// service loads, so orders are checked and charged the same way.
+type Config struct {
+	Strategy    string // Name reported back
+	Symbol      string
+	InitialCash float64
+	Rules       *risk.Engine
+	Fees        *fees.Engine
+}
+
+// Fill is a simulated execution.
+type Fill struct {
+	Time     time.Time `json:"time"`
+	OrderID  uint      `json:"order_id"`
+	Side     string    `json:"side"`
+	Quantity float64   `json:"quantity"`
+	Price    float64   `json:"price"`
+	Fee      float64   `json:"fee"`
+	Maker    bool      `json:"maker"`
+}
+
+// EquityPoint is the account value at a point of the history.
+type EquityPoint struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
+
+// Report is the outcome of a backtest.
+type Report struct {
+	Strategy           string         `json:"strategy"`
+	Symbol             string         `json:"symbol"`
+	From               time.Time      `json:"from"`
+	To                 time.Time      `json:"to"`
+	Ticks              int            `json:"ticks"`
+	InitialCash        float64        `json:"initial_cash"`
+	FinalEquity        float64        `json:"final_equity"`
+	PnL                float64        `json:"pnl"`
+	RealizedPnL        float64        `json:"realized_pnl"`
+	ReturnPercent      float64        `json:"return_percent"`
+	Fees               float64        `json:"fees"`
+	MaxDrawdown        float64        `json:"max_drawdown"`
+	MaxDrawdownPercent float64        `json:"max_drawdown_percent"`
+	SharpeRatio        float64        `json:"sharpe_ratio"` // Annualized, zero risk-free rate
+	Trades             int            `json:"trades"`
+	Orders             int            `json:"orders"`
+	Rejected           int            `json:"rejected"`
+	Rejections         map[string]int `json:"rejections"` // Rejected orders per rule ID, "invalid" for malformed ones
+	EquityCurve        []EquityPoint  `json:"equity_curve"`
+	Fills              []Fill         `json:"fills"`
+}
+
+// simulator is the account and order state of a running backtest.
+type simulator struct {
+	cfg      Config
+	cash     float64
+	position domain.Position
+	open     []*domain.Trade
+	nextID   uint
+	fills    []Fill
+	volume   []Fill // Fills still inside the fee volume window
+	report   *Report
+}
+
+// Run replays the data points, oldest first, through the strategy.
+func Run(points []domain.DataPoint, strategy Strategy, cfg Config) (*Report, error) {
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no data points to replay")
+	}
+	if cfg.Rules == nil || cfg.Fees == nil {
+		return nil, fmt.Errorf("risk rules and fee schedule are required")
+	}
+
+	sim := &simulator{
+		cfg:      cfg,
+		cash:     cfg.InitialCash,
+		position: domain.Position{UserID: userID, Symbol: cfg.Symbol},
+		report: &Report{
+			Strategy:    cfg.Strategy,
+			Symbol:      cfg.Symbol,
+			From:        points[0].Timestamp,
+			To:          points[len(points)-1].Timestamp,
+			Ticks:       len(points),
+			InitialCash: cfg.InitialCash,
+			Rejections:  make(map[string]int),
+		},
+	}
+
+	var history []domain.DataPoint // Points of the last 24 hours
+	var curve []EquityPoint
+	for _, p := range points {
+		history = append(history, p)
+		for len(history) > 0 && history[0].Timestamp.Before(p.Timestamp.Add(-window)) {
+			history = history[1:]
+		}
+		tick := Tick{Time: p.Timestamp, Price: p.Value, Market: stats(history)}
+
+		sim.fillResting(tick)
+		for _, order := range strategy.OnTick(tick, sim.account()) {
+			sim.place(order, tick)
+		}
+		curve = append(curve, EquityPoint{Time: tick.Time, Equity: sim.cash + sim.position.Quantity*tick.Price})
+	}
+
+	sim.finish(curve)
+	return sim.report, nil
+}
+
+// place runs an order through the checks PlaceTrade makes and fills what it
+// can at once.
+func (s *simulator) place(order domain.Trade, tick Tick) {
+	s.report.Orders++
+	if order.Symbol == "" {
+		order.Symbol = s.cfg.Symbol
+	}
+	if err := usecase.ValidateTrade(&order); err != nil {
+		s.reject("invalid")
+		return
+	}
+
+	s.nextID++
+	order.ID = s.nextID
+	order.UserID = userID
+	order.ReferencePrice = tick.Market.Lowest
+	order.CreatedAt = tick.Time
+	if order.Type == domain.OrderTypeTrailingStop {
+		order.Trail(tick.Price)
+	}
+
+	violations := s.cfg.Rules.Evaluate(&order, &risk.Context{
+		Market:        tick.Market,
+		DailyNotional: s.dailyNotional(tick.Time),
+		Position:      s.position.Quantity,
+	})
+	if order.Side == domain.SideBuy {
+		required := risk.Notional(&order, tick.Market)
+		required += s.cfg.Fees.Fee(required, s.volume30d(tick.Time), false, true)
+		if required > s.available()+epsilon {
+			violations = append(violations, risk.Violation{RuleID: usecase.BuyingPowerRuleID})
+		}
+	}
+	if len(violations) > 0 {
+		ruleIDs := make([]string, len(violations))
+		for i, v := range violations {
+			ruleIDs[i] = v.RuleID
+		}
+		s.reject(ruleIDs...)
+		return
+	}
+	order.Status = domain.TradeStatusAccepted
+
+	switch {
+	case order.Type == domain.OrderTypeMarket,
+		order.Type == domain.OrderTypeLimit && crosses(&order, tick.Price):
+		s.fill(&order, tick.Price, tick.Time, false)
+	case domain.IsConditional(order.Type),
+		order.TimeInForce == domain.TimeInForceGTC || order.TimeInForce == domain.TimeInForceDAY:
+		s.open = append(s.open, &order)
+	}
+}
+
+// fillResting fills the limit orders the price crossed, at their limit
+// price, and releases the conditional orders it triggered, filling them at
+// the tick price if they are market orders or cross it.
+func (s *simulator) fillResting(tick Tick) {
+	open := s.open[:0]
+	for _, order := range s.open {
+		switch {
+		case order.Type == domain.OrderTypeLimit && crosses(order, tick.Price):
+			s.fill(order, order.Price, tick.Time, true)
+		case domain.IsConditional(order.Type) && order.StopPrice > 0 && order.Triggered(tick.Price):
+			order.Release(tick.Time)
+			if order.Type == domain.OrderTypeMarket || crosses(order, tick.Price) {
+				s.fill(order, tick.Price, tick.Time, false)
+			} else if order.TimeInForce == domain.TimeInForceGTC || order.TimeInForce == domain.TimeInForceDAY {
+				open = append(open, order)
+			}
+		default:
+			if order.Type == domain.OrderTypeTrailingStop {
+				order.Trail(tick.Price)
+			}
+			open = append(open, order)
+		}
+	}
+	s.open = open
+}
+
+// fill executes the rest of the order against the simulated market.
+func (s *simulator) fill(order *domain.Trade, price float64, at time.Time, maker bool) {
+	quantity := order.RemainingQuantity()
+	notional := quantity * price
+	fee := s.cfg.Fees.Fee(notional, s.volume30d(at), maker, order.Fee == 0)
+
+	if order.Side == domain.SideBuy {
+		s.cash -= notional
+	} else {
+		s.cash += notional
+	}
+	s.cash -= fee
+	s.position.Apply(order.Side, quantity, price)
+
+	order.FilledQuantity = order.Quantity
+	order.Fee += fee
+	order.Status = domain.TradeStatusFilled
+
+	fill := Fill{Time: at, OrderID: order.ID, Side: order.Side, Quantity: quantity, Price: price, Fee: fee, Maker: maker}
+	s.fills = append(s.fills, fill)
+	s.volume = append(s.volume, fill)
+}
+
+// reject counts a rejected order under each rule it broke.
+func (s *simulator) reject(ruleIDs ...string) {
+	s.report.Rejected++
+	for _, id := range ruleIDs {
+		s.report.Rejections[id]++
+	}
+}
+
+func (s *simulator) account() Account {
+	open := make([]domain.Trade, len(s.open))
+	for i, order := range s.open {
+		open[i] = *order
+	}
+	return Account{Cash: s.cash, Position: s.position, Open: open}
+}
+
+// available is the cash not reserved by open buy orders.
+func (s *simulator) available() float64 {
+	available := s.cash
+	for _, order := range s.open {
+		if order.Side == domain.SideBuy {
+			available -= order.RemainingQuantity() * risk.OrderPrice(order)
+		}
+	}
+	return available
+}
+
+// dailyNotional is the value traded since midnight UTC of the given time.
+func (s *simulator) dailyNotional(at time.Time) float64 {
+	at = at.UTC()
+	midnight := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	var total float64
+	for i := len(s.fills) - 1; i >= 0 && !s.fills[i].Time.Before(midnight); i-- {
+		total += s.fills[i].Quantity * s.fills[i].Price
+	}
+	return total
+}
+
+// volume30d is the value traded in the fee volume window before the given
+// time.
+func (s *simulator) volume30d(at time.Time) float64 {
+	for len(s.volume) > 0 && s.volume[0].Time.Before(at.Add(-feeVolumeWindow)) {
+		s.volume = s.volume[1:]
+	}
+	var total float64
+	for _, f := range s.volume {
+		total += f.Quantity * f.Price
+	}
+	return total
+}
+
+// finish computes the report metrics from the equity curve.
+func (s *simulator) finish(curve []EquityPoint) {
+	r := s.report
+	r.FinalEquity = curve[len(curve)-1].Equity
+	r.PnL = r.FinalEquity - r.InitialCash
+	r.RealizedPnL = s.position.RealizedPnL
+	if r.InitialCash > 0 {
+		r.ReturnPercent = r.PnL / r.InitialCash * 100
+	}
+	r.Trades = len(s.fills)
+	r.Fills = append([]Fill{}, s.fills...)
+	for _, f := range s.fills {
+		r.Fees += f.Fee
+	}
+
+	peak := curve[0].Equity
+	for _, p := range curve {
+		peak = math.Max(peak, p.Equity)
+		if drawdown := peak - p.Equity; drawdown > r.MaxDrawdown {
+			r.MaxDrawdown = drawdown
+			if peak > 0 {
+				r.MaxDrawdownPercent = drawdown / peak * 100
+			}
+		}
+	}
+
+	r.SharpeRatio = sharpe(curve)
+	r.EquityCurve = downsample(curve, maxCurvePoints)
+}
+
+// sharpe annualizes the mean over the standard deviation of the returns
+// between the points of the equity curve.
+func sharpe(curve []EquityPoint) float64 {
+	if len(curve) < 3 {
+		return 0
+	}
+
+	returns := make([]float64, 0, len(curve)-1)
+	for i := 1; i < len(curve); i++ {
+		if curve[i-1].Equity <= 0 {
+			return 0
+		}
+		returns = append(returns, curve[i].Equity/curve[i-1].Equity-1)
+	}
+
+	mean := average(returns)
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+
+	span := curve[len(curve)-1].Time.Sub(curve[0].Time)
+	if std == 0 || span <= 0 {
+		return 0
+	}
+	periodsPerYear := float64(365*24*time.Hour) / (float64(span) / float64(len(returns)))
+	return mean / std * math.Sqrt(periodsPerYear)
+}
+
+// downsample keeps at most max points of the curve, always including the
+// last one.
+func downsample(curve []EquityPoint, max int) []EquityPoint {
+	if len(curve) <= max {
+		return curve
+	}
+	step := float64(len(curve)-1) / float64(max-1)
+	sampled := make([]EquityPoint, 0, max)
+	for i := 0; i < max; i++ {
+		sampled = append(sampled, curve[int(math.Round(float64(i)*step))])
+	}
+	return sampled
+}
+
+// stats computes the lowest, highest and last price of the points.
+func stats(points []domain.DataPoint) domain.PriceStats {
+	s := domain.PriceStats{Lowest: points[0].Value, Highest: points[0].Value, Last: points[len(points)-1].Value}
+	for _, p := range points {
+		s.Lowest = math.Min(s.Lowest, p.Value)
+		s.Highest = math.Max(s.Highest, p.Value)
+	}
+	return s
+}
+
+func crosses(order *domain.Trade, price float64) bool {
+	if order.Side == domain.SideBuy {
+		return price <= order.Price
+	}
+	return price >= order.Price
+}
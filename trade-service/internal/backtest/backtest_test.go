@@ -0,0 +1,71 @@
+package backtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/risk"
+)
+
+func testConfig(t *testing.T, cash float64, configs ...risk.RuleConfig) Config {
+	rules, err := risk.NewEngineWithRules(configs)
+	if err != nil {
+		t.Fatalf("failed to build rules: %v", err)
+	}
+	feeEngine, err := fees.NewEngineWithSchedule(fees.Schedule{Flat: 1})
+	if err != nil {
+		t.Fatalf("failed to build fees: %v", err)
+	}
+	return Config{Strategy: "test", Symbol: "BTCUSD", InitialCash: cash, Rules: rules, Fees: feeEngine}
+}
+
+func TestRunBuyAndHold(t *testing.T) {
+	points, err := LoadCSV(strings.NewReader("timestamp,value\n" +
+		"2024-01-01T00:00:00Z,100\n" +
+		"2024-01-01T01:00:00Z,110\n" +
+		"2024-01-01T02:00:00Z,90\n" +
+		"2024-01-01T03:00:00Z,120\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	strategy, _ := NewStrategy("buy_and_hold", Params{Symbol: "BTCUSD", Quantity: 2})
+	report, err := Run(points, strategy, testConfig(t, 1000))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Bought 2 @ 100 for a flat fee of 1: equity 799 + 2 × price
+	if report.Trades != 1 || report.Fees != 1 {
+		t.Errorf("expected one fill paying the flat fee, got %d fills, %.2f fees", report.Trades, report.Fees)
+	}
+	if report.FinalEquity != 1039 || report.PnL != 39 {
+		t.Errorf("unexpected equity %.2f, pnl %.2f", report.FinalEquity, report.PnL)
+	}
+	// From the 1019 peak down to 979
+	if report.MaxDrawdown != 40 {
+		t.Errorf("expected a drawdown of 40, got %.2f", report.MaxDrawdown)
+	}
+	if len(report.EquityCurve) != 4 || report.SharpeRatio == 0 {
+		t.Errorf("unexpected curve %+v, sharpe %.2f", report.EquityCurve, report.SharpeRatio)
+	}
+}
+
+func TestRunAppliesLiveChecks(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []domain.DataPoint{{Value: 100, Timestamp: start}, {Value: 100, Timestamp: start.Add(time.Hour)}}
+
+	// Too little cash, and an order above the notional limit
+	strategy, _ := NewStrategy("buy_and_hold", Params{Symbol: "BTCUSD", Quantity: 20})
+	report, err := Run(points, strategy, testConfig(t, 1000,
+		risk.RuleConfig{ID: "max-notional", Type: risk.RuleMaxOrderNotional, Limit: 500}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Trades != 0 || report.Rejected != 1 || report.Rejections["max-notional"] != 1 || report.Rejections["buying-power"] != 1 {
+		t.Errorf("unexpected rejections: %+v", report)
+	}
+}
@@ -0,0 +1,98 @@
+package backtest
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"simpletrading/tradeservice/internal/domain"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// LoadDB reads the data points recorded between from and to (zero for no
+// bound) from a Data Service SQLite database, oldest first.
+func LoadDB(path string, from, to time.Time) ([]domain.DataPoint, error) {
+	sqlDB, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to data database: %v", err)
+	}
+
+	query := db.Model(&domain.DataPoint{})
+	if !from.IsZero() {
+		query = query.Where("timestamp >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("timestamp < ?", to)
+	}
+
+	var points []domain.DataPoint
+	if err := query.Order("timestamp asc, id asc").Find(&points).Error; err != nil {
+		return nil, fmt.Errorf("failed to read data points: %v", err)
+	}
+	return points, nil
+}
+
+// LoadCSV reads data points from a CSV export with a header row naming a
+// "timestamp" (RFC3339) and a "value" column; an "id" column is optional.
+// Points are returned oldest first.
+func LoadCSV(r io.Reader) ([]domain.DataPoint, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+
+	columns := map[string]int{"id": -1, "timestamp": -1, "value": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok {
+			columns[name] = i
+		}
+	}
+	if columns["timestamp"] < 0 || columns["value"] < 0 {
+		return nil, fmt.Errorf("CSV needs timestamp and value columns")
+	}
+
+	var points []domain.DataPoint
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		var dp domain.DataPoint
+		if dp.Timestamp, err = time.Parse(time.RFC3339, strings.TrimSpace(record[columns["timestamp"]])); err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp: %v", line, err)
+		}
+		if dp.Value, err = strconv.ParseFloat(strings.TrimSpace(record[columns["value"]]), 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid value: %v", line, err)
+		}
+		if i := columns["id"]; i >= 0 {
+			id, err := strconv.ParseUint(strings.TrimSpace(record[i]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid id: %v", line, err)
+			}
+			dp.ID = uint(id)
+		}
+		points = append(points, dp)
+	}
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+	return points, nil
+}
@@ -0,0 +1,109 @@
+package backtest
+
+import (
+	"fmt"
+	"time"
+
+	"simpletrading/tradeservice/internal/domain"
+)
+
+// Tick is one price of the replayed history together with the prices of
+// the 24 hours up to it, as the live service would have seen them.
+type Tick struct {
+	Time   time.Time
+	Price  float64
+	Market domain.PriceStats
+}
+
+// Account is the simulated account as a strategy sees it.
+type Account struct {
+	Cash     float64
+	Position domain.Position
+	Open     []domain.Trade // Orders waiting to be filled
+}
+
+// Strategy decides which orders to place as the history is replayed.
+// Orders go through the same validation and risk rules as live ones.
+type Strategy interface {
+	OnTick(tick Tick, account Account) []domain.Trade
+}
+
+// Params configures the built-in strategies.
+type Params struct {
+	Symbol   string
+	Quantity float64 // Order size
+	Fast     int     // Ticks in the fast moving average of sma_cross
+	Slow     int     // Ticks in the slow moving average of sma_cross
+}
+
+// NewStrategy returns a built-in strategy by name: "buy_and_hold" or
+// "sma_cross".
+func NewStrategy(name string, params Params) (Strategy, error) {
+	if params.Quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+
+	switch name {
+	case "buy_and_hold":
+		return &buyAndHold{params: params}, nil
+	case "sma_cross":
+		if params.Fast <= 0 || params.Slow <= params.Fast {
+			return nil, fmt.Errorf("sma_cross needs 0 < fast < slow")
+		}
+		return &smaCross{params: params}, nil
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", name)
+	}
+}
+
+// buyAndHold buys once at the first tick and holds.
+type buyAndHold struct {
+	params Params
+	bought bool
+}
+
+func (s *buyAndHold) OnTick(tick Tick, account Account) []domain.Trade {
+	if s.bought {
+		return nil
+	}
+	s.bought = true
+	return []domain.Trade{marketOrder(s.params.Symbol, domain.SideBuy, s.params.Quantity)}
+}
+
+// smaCross goes long when the fast moving average crosses above the slow
+// one and closes the position when it crosses back below.
+type smaCross struct {
+	params Params
+	prices []float64
+}
+
+func (s *smaCross) OnTick(tick Tick, account Account) []domain.Trade {
+	s.prices = append(s.prices, tick.Price)
+	if len(s.prices) > s.params.Slow {
+		s.prices = s.prices[1:]
+	}
+	if len(s.prices) < s.params.Slow {
+		return nil
+	}
+
+	fast, slow := average(s.prices[len(s.prices)-s.params.Fast:]), average(s.prices)
+	switch {
+	case fast > slow && account.Position.Quantity == 0:
+		return []domain.Trade{marketOrder(s.params.Symbol, domain.SideBuy, s.params.Quantity)}
+	case fast < slow && account.Position.Quantity > 0:
+		return []domain.Trade{marketOrder(s.params.Symbol, domain.SideSell, account.Position.Quantity)}
+	}
+	return nil
+}
+
+func marketOrder(symbol, side string, quantity float64) domain.Trade {
+	return domain.Trade{Symbol: symbol, Side: side, Type: domain.OrderTypeMarket, Quantity: quantity}
+}
+
+func average(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,83 @@
+package bots
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"simpletrading/tradeservice/internal/domain"
+)
+
+func init() {
+	Register("mean_reversion", newMeanReversion)
+}
+
+// meanReversionParams configures the mean_reversion strategy.
+type meanReversionParams struct {
+	Symbol    string  `json:"symbol"`
+	Quantity  float64 `json:"quantity"`  // Order size
+	Window    int     `json:"window"`    // Prices in the moving average
+	Threshold float64 `json:"threshold"` // Distance from the average, in percent, that triggers an order
+}
+
+// meanReversion buys when the price falls the threshold below its moving
+// average and sells the position once the price is back above the average.
+type meanReversion struct {
+	params   meanReversionParams
+	prices   []float64
+	position float64
+	filled   map[uint]float64 // Quantity already seen filled per order
+}
+
+func newMeanReversion(raw json.RawMessage) (Strategy, error) {
+	params := meanReversionParams{Symbol: "BTCUSD", Window: 20, Threshold: 1}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, fmt.Errorf("invalid mean_reversion params: %v", err)
+		}
+	}
+	if params.Quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	if params.Window < 2 || params.Threshold <= 0 {
+		return nil, fmt.Errorf("mean_reversion needs a window of at least 2 and a positive threshold")
+	}
+	return &meanReversion{params: params, filled: make(map[uint]float64)}, nil
+}
+
+func (s *meanReversion) OnPrice(point domain.DataPoint) []domain.Trade {
+	s.prices = append(s.prices, point.Value)
+	if len(s.prices) > s.params.Window {
+		s.prices = s.prices[1:]
+	}
+	if len(s.prices) < s.params.Window {
+		return nil
+	}
+
+	var sum float64
+	for _, p := range s.prices {
+		sum += p
+	}
+	mean := sum / float64(len(s.prices))
+
+	switch {
+	case s.position == 0 && point.Value <= mean*(1-s.params.Threshold/100):
+		return []domain.Trade{{Symbol: s.params.Symbol, Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: s.params.Quantity}}
+	case s.position > 0 && point.Value >= mean:
+		return []domain.Trade{{Symbol: s.params.Symbol, Side: domain.SideSell, Type: domain.OrderTypeMarket, Quantity: s.position}}
+	}
+	return nil
+}
+
+func (s *meanReversion) OnFill(trade domain.Trade) {
+	quantity := trade.FilledQuantity - s.filled[trade.ID]
+	s.filled[trade.ID] = trade.FilledQuantity
+	if !domain.IsOpen(trade.Status) {
+		delete(s.filled, trade.ID)
+	}
+
+	if trade.Side == domain.SideBuy {
+		s.position += quantity
+	} else {
+		s.position -= quantity
+	}
+}
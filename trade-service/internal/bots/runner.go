@@ -0,0 +1,381 @@
+package bots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+var (
+	ErrInvalidBot  = errors.New("invalid bot")
+	ErrBotRunning  = errors.New("bot is already running")
+	ErrBotNotFound = errors.New("bot not found")
+)
+
+var botName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Ticks streams prices as they arrive.
+type Ticks interface {
+	Subscribe() (<-chan domain.DataPoint, func())
+}
+
+// StartRequest describes a bot to start.
+type StartRequest struct {
+	Name     string
+	Strategy string
+	Params   []byte        // JSON parameters handed to the strategy's factory
+	Interval time.Duration // Zero runs the strategy on every streamed price
+	Mode     string        // "live" or "paper"; empty keeps the bot identity's mode
+}
+
+// Status is a running bot as reported by the API.
+type Status struct {
+	Name          string    `json:"name"`
+	Strategy      string    `json:"strategy"`
+	UserID        string    `json:"user_id"`
+	Mode          string    `json:"mode"`
+	Interval      string    `json:"interval,omitempty"` // Empty when the bot runs on every streamed price
+	StartedBy     string    `json:"started_by"`
+	StartedAt     time.Time `json:"started_at"`
+	Orders        int       `json:"orders"`
+	Rejected      int       `json:"rejected"`
+	Fills         int       `json:"fills"`
+	LastError     string    `json:"last_error,omitempty"`
+	RealizedPnL   float64   `json:"realized_pnl"`
+	UnrealizedPnL *float64  `json:"unrealized_pnl"` // Null if no price was available
+}
+
+// bot is a running strategy with the state the runner keeps for it.
+type bot struct {
+	status   Status
+	strategy Strategy
+	uc       *usecase.TradeUsecase
+	interval time.Duration
+	open     map[uint]float64 // Filled quantity of the bot's open orders, as last seen
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu sync.Mutex // Guards status while the bot runs
+}
+
+// Runner runs registered strategies as bots. Each bot trades under its own
+// identity, "bot:<name>", through the live or the paper usecase.
+type Runner struct {
+	live   *usecase.TradeUsecase
+	paper  *usecase.TradeUsecase
+	prices usecase.PriceSource
+	ticks  Ticks // Nil without a price stream
+
+	mu   sync.Mutex
+	bots map[string]*bot
+}
+
+func NewRunner(live, paper *usecase.TradeUsecase, prices usecase.PriceSource, ticks Ticks) *Runner {
+	return &Runner{live: live, paper: paper, prices: prices, ticks: ticks, bots: make(map[string]*bot)}
+}
+
+// UserID returns the identity the bot with the given name trades under.
+func UserID(name string) string {
+	return "bot:" + name
+}
+
+// CancelLeftoverOrders cancels the orders every bot identity still has open,
+// live and paper. Bots run in memory only and do not survive a restart, so
+// it is called at startup, before serving: nothing could stop the orders of
+// a bot that is no longer running.
+func (r *Runner) CancelLeftoverOrders() error {
+	for _, uc := range []*usecase.TradeUsecase{r.live, r.paper} {
+		cancelled, err := uc.CancelOpenOrdersByUserPrefix(UserID(""), "cancelled at restart, bot no longer running")
+		if err != nil {
+			return fmt.Errorf("failed to cancel %s bot orders: %v", uc.Mode(), err)
+		}
+		if cancelled > 0 {
+			log.Printf("Cancelled %d %s orders left by bots", cancelled, uc.Mode())
+		}
+	}
+	return nil
+}
+
+// Start creates the bot's strategy and runs it until Stop is called.
+func (r *Runner) Start(startedBy string, req StartRequest) (*Status, error) {
+	if !botName.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must be 1-64 lowercase letters, digits, '-' or '_'", ErrInvalidBot)
+	}
+	if req.Interval < 0 {
+		return nil, fmt.Errorf("%w: interval must not be negative", ErrInvalidBot)
+	}
+	if req.Interval == 0 && r.ticks == nil {
+		return nil, fmt.Errorf("%w: no price stream is configured; set an interval", ErrInvalidBot)
+	}
+	strategy, err := newStrategy(req.Strategy, req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.bots[req.Name]; ok {
+		return nil, ErrBotRunning
+	}
+
+	userID := UserID(req.Name)
+	uc, err := r.usecase(userID, req.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &bot{
+		status: Status{
+			Name:      req.Name,
+			Strategy:  req.Strategy,
+			UserID:    userID,
+			Mode:      uc.Mode(),
+			StartedBy: startedBy,
+			StartedAt: time.Now(),
+		},
+		strategy: strategy,
+		uc:       uc,
+		interval: req.Interval,
+		open:     make(map[uint]float64),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	if req.Interval > 0 {
+		b.status.Interval = req.Interval.String()
+	}
+	r.bots[req.Name] = b
+	go r.run(ctx, b)
+
+	log.Printf("Bot started: %s (%s, %s) by %s", req.Name, req.Strategy, b.status.Mode, startedBy)
+
+	status := b.snapshot()
+	return &status, nil
+}
+
+// Stop stops the bot and cancels the orders it still has open.
+func (r *Runner) Stop(ctx context.Context, name string) (*Status, error) {
+	r.mu.Lock()
+	b, ok := r.bots[name]
+	delete(r.bots, name)
+	r.mu.Unlock()
+	if !ok {
+		return nil, ErrBotNotFound
+	}
+
+	b.cancel()
+	<-b.done
+	b.cancelOrders()
+
+	log.Printf("Bot stopped: %s", name)
+
+	return r.withPnL(ctx, b), nil
+}
+
+// retire takes a bot that stopped by itself off the registry and cancels the
+// orders it still has open, as Stop does. If Stop got to the bot first, it
+// does both instead.
+func (r *Runner) retire(b *bot) {
+	r.mu.Lock()
+	current, ok := r.bots[b.status.Name]
+	ok = ok && current == b
+	if ok {
+		delete(r.bots, b.status.Name)
+	}
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	b.cancelOrders()
+
+	log.Printf("Bot stopped: %s", b.status.Name)
+}
+
+// List returns the running bots, by name, with their P&L.
+func (r *Runner) List(ctx context.Context) []Status {
+	r.mu.Lock()
+	running := make([]*bot, 0, len(r.bots))
+	for _, b := range r.bots {
+		running = append(running, b)
+	}
+	r.mu.Unlock()
+
+	statuses := make([]Status, 0, len(running))
+	for _, b := range running {
+		statuses = append(statuses, *r.withPnL(ctx, b))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// usecase picks the usecase for the bot identity's trading mode, switching
+// the identity to the requested mode first.
+func (r *Runner) usecase(userID, mode string) (*usecase.TradeUsecase, error) {
+	if mode != "" {
+		if err := r.live.SetTradingMode(r.paper, userID, mode); err != nil {
+			if errors.Is(err, usecase.ErrInvalidTradingMode) {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidBot, err)
+			}
+			return nil, err
+		}
+	}
+
+	current, err := r.live.TradingMode(userID)
+	if err != nil {
+		return nil, err
+	}
+	if current != domain.TradingModePaper {
+		return r.live, nil
+	}
+	if err := r.paper.FundPaperAccount(userID); err != nil {
+		return nil, err
+	}
+	return r.paper, nil
+}
+
+// run feeds the strategy prices until the bot is stopped. A bot whose
+// strategy panicked is retired.
+func (r *Runner) run(ctx context.Context, b *bot) {
+	defer close(b.done)
+
+	if b.interval == 0 {
+		ticks, unsubscribe := r.ticks.Subscribe()
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case point := <-ticks:
+				if !r.step(ctx, b, point) {
+					r.retire(b)
+					return
+				}
+			}
+		}
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			stats, _, err := r.prices.PriceStats(ctx)
+			if err != nil {
+				b.failed(fmt.Errorf("failed to get prices: %v", err))
+				continue
+			}
+			if !r.step(ctx, b, domain.DataPoint{Value: stats.Last, Timestamp: now}) {
+				r.retire(b)
+				return
+			}
+		}
+	}
+}
+
+// step reports the fills since the last price to the strategy, then places
+// the orders it returns for the new price. A strategy that panics is
+// stopped and step returns false.
+func (r *Runner) step(ctx context.Context, b *bot, point domain.DataPoint) (ok bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			b.failed(fmt.Errorf("strategy panicked: %v; bot stopped", p))
+			ok = false
+		}
+	}()
+
+	for id, filled := range b.open {
+		trade, err := b.uc.GetTrade(b.status.UserID, id)
+		if err != nil {
+			b.failed(fmt.Errorf("failed to check order %d: %v", id, err))
+			continue
+		}
+		b.track(trade, filled)
+	}
+
+	for _, order := range b.strategy.OnPrice(point) {
+		b.mu.Lock()
+		b.status.Orders++
+		b.mu.Unlock()
+
+		trade, _, err := b.uc.PlaceTrade(ctx, b.status.UserID, order)
+		if err != nil {
+			var rejection *usecase.RejectionError
+			if errors.As(err, &rejection) {
+				b.mu.Lock()
+				b.status.Rejected++
+				b.mu.Unlock()
+			}
+			b.failed(err)
+			continue
+		}
+		b.track(trade, 0)
+	}
+	return true
+}
+
+// track tells the strategy about the order's new fills and remembers the
+// order while it stays open.
+func (b *bot) track(trade *domain.Trade, seen float64) {
+	if trade.FilledQuantity > seen {
+		b.mu.Lock()
+		b.status.Fills++
+		b.mu.Unlock()
+		b.strategy.OnFill(*trade)
+	}
+
+	if domain.IsOpen(trade.Status) {
+		b.open[trade.ID] = trade.FilledQuantity
+	} else {
+		delete(b.open, trade.ID)
+	}
+}
+
+// cancelOrders cancels the orders the bot still has open. The bot must not
+// be running.
+func (b *bot) cancelOrders() {
+	for id := range b.open {
+		if _, err := b.uc.CancelOrder(b.status.UserID, id); err != nil {
+			log.Printf("Bot %s: failed to cancel order %d: %v", b.status.Name, id, err)
+		}
+	}
+}
+
+func (b *bot) failed(err error) {
+	log.Printf("Bot %s: %v", b.status.Name, err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status.LastError = err.Error()
+}
+
+func (b *bot) snapshot() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
+
+// withPnL returns the bot's status with the P&L of its identity's
+// positions.
+func (r *Runner) withPnL(ctx context.Context, b *bot) *Status {
+	status := b.snapshot()
+	portfolio, err := b.uc.GetPortfolio(ctx, status.UserID)
+	if err != nil {
+		log.Printf("Bot %s: failed to get portfolio: %v", status.Name, err)
+		return &status
+	}
+	status.RealizedPnL = portfolio.RealizedPnL
+	status.UnrealizedPnL = portfolio.UnrealizedPnL
+	return &status
+}
@@ -0,0 +1,188 @@
+package bots
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"simpletrading/tradeservice/internal/calendar"
+	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/matching"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"simpletrading/tradeservice/internal/risk"
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+type staticPrices domain.PriceStats
+
+func (s staticPrices) PriceStats(ctx context.Context) (domain.PriceStats, bool, error) {
+	return domain.PriceStats(s), false, nil
+}
+
+func (s staticPrices) PriceActivity(ctx context.Context, days int, bucket time.Duration) (map[int]float64, error) {
+	return nil, nil
+}
+
+// restingBuyer rests one buy order at the first price and counts its fills.
+type restingBuyer struct {
+	placed bool
+	fills  []domain.Trade
+}
+
+func (s *restingBuyer) OnPrice(point domain.DataPoint) []domain.Trade {
+	if s.placed {
+		return nil
+	}
+	s.placed = true
+	return []domain.Trade{{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeLimit, Price: point.Value, Quantity: 2, TimeInForce: domain.TimeInForceGTC}}
+}
+
+func (s *restingBuyer) OnFill(trade domain.Trade) {
+	s.fills = append(s.fills, trade)
+}
+
+// panicking rests one buy order at the first price and panics at the next.
+type panicking struct {
+	restingBuyer
+}
+
+func (s *panicking) OnPrice(point domain.DataPoint) []domain.Trade {
+	if s.placed {
+		panic("boom")
+	}
+	return s.restingBuyer.OnPrice(point)
+}
+
+// newTestRunner creates a runner with empty live and paper books and returns
+// it with the live usecase.
+func newTestRunner(t *testing.T) (*Runner, *usecase.TradeUsecase) {
+	cfg := &config.Config{}
+	rules, _ := risk.NewEngine("")
+	feeEngine, _ := fees.NewEngine("")
+	sessions, _ := calendar.NewEngine("")
+	prices := staticPrices{Lowest: 100, Highest: 100, Last: 100}
+	halts := usecase.NewHaltUsecase(memory.NewHaltRepository(config.InitDatabase(filepath.Join(t.TempDir(), "halts.db"))), cfg)
+	newUsecase := func(name string) *usecase.TradeUsecase {
+		repo := memory.NewTradeRepository(config.InitDatabase(filepath.Join(t.TempDir(), name)))
+		return usecase.NewTradeUsecase(repo, matching.NewEngine(), rules, feeEngine, sessions, halts, prices, cfg)
+	}
+	live := newUsecase("trade.db")
+	return NewRunner(live, newUsecase("paper.db"), prices, nil), live
+}
+
+func TestRunnerReportsFillsAndStops(t *testing.T) {
+	runner, live := newTestRunner(t)
+
+	strategy := &restingBuyer{}
+	Register("test_resting_buyer", func(json.RawMessage) (Strategy, error) { return strategy, nil })
+
+	if _, err := runner.Start("admin@example.com", StartRequest{Name: "ticks", Strategy: "test_resting_buyer"}); err == nil {
+		t.Error("expected a bot on every tick to need a price stream")
+	}
+	if _, err := runner.Start("admin@example.com", StartRequest{Name: "Bad Name", Strategy: "mean_reversion", Interval: time.Hour}); err == nil {
+		t.Error("expected an invalid name to be refused")
+	}
+
+	// A long interval keeps the bot idle, so the test drives it
+	if _, err := runner.Start("admin@example.com", StartRequest{Name: "buyer", Strategy: "test_resting_buyer", Interval: time.Hour}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := runner.bots["buyer"]
+	ctx := context.Background()
+
+	runner.step(ctx, b, domain.DataPoint{Value: 100, Timestamp: time.Now()})
+	if len(strategy.fills) != 0 || len(b.open) != 1 {
+		t.Fatalf("expected one resting order, got fills %+v, open %+v", strategy.fills, b.open)
+	}
+
+	// Someone sells into the bot's order; the bot hears of it at the next price
+	sell := domain.Trade{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeLimit, Price: 100, Quantity: 1, TimeInForce: domain.TimeInForceGTC}
+	if _, _, err := live.PlaceTrade(ctx, "alice@example.com", sell); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runner.step(ctx, b, domain.DataPoint{Value: 100, Timestamp: time.Now()})
+	if len(strategy.fills) != 1 || strategy.fills[0].FilledQuantity != 1 {
+		t.Fatalf("expected one partial fill, got %+v", strategy.fills)
+	}
+
+	statuses := runner.List(ctx)
+	if len(statuses) != 1 || statuses[0].UserID != "bot:buyer" || statuses[0].Orders != 1 || statuses[0].Fills != 1 {
+		t.Errorf("unexpected statuses: %+v", statuses)
+	}
+
+	// Stopping cancels the rest of the order
+	if _, err := runner.Stop(ctx, "buyer"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orders, _, _ := live.ListTrades("bot:buyer", domain.TradeFilter{})
+	if len(orders) != 1 || orders[0].Status != domain.TradeStatusCancelled {
+		t.Errorf("expected the bot's order to be cancelled, got %+v", orders)
+	}
+	if _, err := runner.Stop(ctx, "buyer"); err != ErrBotNotFound {
+		t.Errorf("expected ErrBotNotFound, got %v", err)
+	}
+}
+
+func TestRunnerRetiresPanickedBot(t *testing.T) {
+	runner, live := newTestRunner(t)
+	Register("test_panicking", func(json.RawMessage) (Strategy, error) { return &panicking{}, nil })
+
+	if _, err := runner.Start("admin@example.com", StartRequest{Name: "crasher", Strategy: "test_panicking", Interval: 5 * time.Millisecond}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runner.mu.Lock()
+	b := runner.bots["crasher"]
+	runner.mu.Unlock()
+	select {
+	case <-b.done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the bot to stop after its strategy panicked")
+	}
+
+	ctx := context.Background()
+	if statuses := runner.List(ctx); len(statuses) != 0 {
+		t.Errorf("expected the bot to be gone, got %+v", statuses)
+	}
+	if b.snapshot().LastError == "" {
+		t.Error("expected the panic to be recorded")
+	}
+	orders, _, _ := live.ListTrades("bot:crasher", domain.TradeFilter{})
+	if len(orders) != 1 || orders[0].Status != domain.TradeStatusCancelled {
+		t.Errorf("expected the bot's order to be cancelled, got %+v", orders)
+	}
+
+	// The name is free again
+	if _, err := runner.Start("admin@example.com", StartRequest{Name: "crasher", Strategy: "test_panicking", Interval: time.Hour}); err != nil {
+		t.Errorf("expected the bot to start again, got %v", err)
+	}
+	runner.Stop(ctx, "crasher")
+}
+
+func TestRunnerCancelsLeftoverOrders(t *testing.T) {
+	runner, live := newTestRunner(t)
+	ctx := context.Background()
+
+	// Orders a bot left open before a restart, next to a user's order
+	buy := domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeLimit, Price: 90, Quantity: 1, TimeInForce: domain.TimeInForceGTC}
+	for _, userID := range []string{UserID("old"), "alice@example.com"} {
+		if _, _, err := live.PlaceTrade(ctx, userID, buy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := runner.CancelLeftoverOrders(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orders, _, _ := live.ListTrades(UserID("old"), domain.TradeFilter{})
+	if len(orders) != 1 || orders[0].Status != domain.TradeStatusCancelled {
+		t.Errorf("expected the bot's order to be cancelled, got %+v", orders)
+	}
+	orders, _, _ = live.ListTrades("alice@example.com", domain.TradeFilter{})
+	if len(orders) != 1 || orders[0].Status != domain.TradeStatusAccepted {
+		t.Errorf("expected the user's order to stay open, got %+v", orders)
+	}
+}
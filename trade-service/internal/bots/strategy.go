@@ -0,0 +1,72 @@
+package bots
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"simpletrading/tradeservice/internal/domain"
+)
+
+// Strategy is the logic of a trading bot. The runner calls a bot's strategy
+// from a single goroutine, so implementations need no locking.
+type Strategy interface {
+	// OnPrice is called with every price the bot sees and returns the
+	// orders to place. They go through the same validation, risk rules and
+	// buying power check as orders sent to POST /trade.
+	OnPrice(point domain.DataPoint) []domain.Trade
+	// OnFill is called each time more of one of the bot's orders fills,
+	// with the order as it stands after the fill.
+	OnFill(trade domain.Trade)
+}
+
+// Factory creates a strategy from the parameters a bot is started with.
+type Factory func(params json.RawMessage) (Strategy, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a strategy available to bots under the given name. It is
+// meant to be called from the init function of the file defining the
+// strategy, and panics if the name is already taken.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic("bots: strategy registered twice: " + name)
+	}
+	registry[name] = factory
+}
+
+// Strategies returns the names of the registered strategies, sorted.
+func Strategies() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newStrategy(name string, params json.RawMessage) (Strategy, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown strategy %q; registered: %s", ErrInvalidBot, name, strings.Join(Strategies(), ", "))
+	}
+
+	strategy, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBot, err)
+	}
+	return strategy, nil
+}
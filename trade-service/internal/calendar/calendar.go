@@ -0,0 +1,237 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchDays is how far ahead a session is looked for.
+const searchDays = 370
+
+// Schedule describes the sessions of a market: one per trading day, from
+// Open to Close in the schedule's time zone. The zero Schedule trades around
+// the clock in UTC days.
+type Schedule struct {
+	Timezone string            `json:"timezone"`  // IANA zone name, UTC if empty
+	Open     string            `json:"open"`      // Session open "15:04", midnight if empty
+	Close    string            `json:"close"`     // Session close "15:04", "24:00" (end of day) if empty
+	Days     []string          `json:"days"`      // Trading weekdays ("mon" to "sun"), every day if empty
+	Holidays []string          `json:"holidays"`  // Dates "2006-01-02" with no session
+	HalfDays map[string]string `json:"half_days"` // Date "2006-01-02" to its early close "15:04"
+}
+
+// Calendar holds the default schedule and the symbols that trade on their
+// own.
+type Calendar struct {
+	Default Schedule            `json:"default"`
+	Symbols map[string]Schedule `json:"symbols"`
+}
+
+// Session is one trading session, open from Open up to, not including,
+// Close.
+type Session struct {
+	Open  time.Time `json:"open"`
+	Close time.Time `json:"close"`
+}
+
+// Contains reports whether the session is open at t.
+func (s Session) Contains(t time.Time) bool {
+	return !t.Before(s.Open) && t.Before(s.Close)
+}
+
+// schedule is a validated Schedule.
+type schedule struct {
+	loc      *time.Location
+	open     time.Duration // Since midnight
+	close    time.Duration
+	days     [7]bool
+	holidays map[string]bool
+	halfDays map[string]time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func compile(s Schedule) (*schedule, error) {
+	c := &schedule{loc: time.UTC, close: 24 * time.Hour, holidays: make(map[string]bool), halfDays: make(map[string]time.Duration)}
+
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %v", s.Timezone, err)
+		}
+		c.loc = loc
+	}
+
+	var err error
+	if s.Open != "" {
+		if c.open, err = clock(s.Open); err != nil {
+			return nil, err
+		}
+	}
+	if s.Close != "" {
+		if c.close, err = clock(s.Close); err != nil {
+			return nil, err
+		}
+	}
+	if c.close <= c.open {
+		return nil, fmt.Errorf("close %s must be after open %s", s.Close, s.Open)
+	}
+
+	if len(s.Days) == 0 {
+		c.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, day := range s.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		c.days[weekday] = true
+	}
+
+	for _, date := range s.Holidays {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q", date)
+		}
+		c.holidays[date] = true
+	}
+	for date, close := range s.HalfDays {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("invalid half day %q", date)
+		}
+		d, err := clock(close)
+		if err != nil {
+			return nil, err
+		}
+		if d <= c.open || d > c.close {
+			return nil, fmt.Errorf("half day %s: close %s must be within the session", date, close)
+		}
+		c.halfDays[date] = d
+	}
+	return c, nil
+}
+
+// clock parses "15:04" into the time since midnight. "24:00" is the end of
+// the day.
+func clock(v string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(v, ":")
+	h, herr := strconv.Atoi(hours)
+	m, merr := strconv.Atoi(minutes)
+	if !ok || herr != nil || merr != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m > 0) {
+		return 0, fmt.Errorf("invalid time %q: must be HH:MM", v)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// session returns the first session that has not closed at t.
+func (c *schedule) session(t time.Time) (Session, bool) {
+	local := t.In(c.loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.loc)
+	for i := 0; i < searchDays; i++ {
+		date := day.AddDate(0, 0, i)
+		key := date.Format(time.DateOnly)
+		if !c.days[date.Weekday()] || c.holidays[key] {
+			continue
+		}
+		close := c.close
+		if d, ok := c.halfDays[key]; ok {
+			close = d
+		}
+		session := Session{Open: date.Add(c.open), Close: date.Add(close)}
+		if t.Before(session.Close) {
+			return session, true
+		}
+	}
+	return Session{}, false
+}
+
+// Engine holds the trading calendar. It can be reloaded from the calendar
+// file while the service is running.
+type Engine struct {
+	mu       sync.RWMutex
+	path     string
+	fallback *schedule
+	symbols  map[string]*schedule
+}
+
+// NewEngine loads the calendar from the given JSON file, or trades every
+// symbol around the clock if the path is empty.
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewEngineWithCalendar builds an engine from a calendar that is not read
+// from a file.
+func NewEngineWithCalendar(cal Calendar) (*Engine, error) {
+	e := &Engine{}
+	if err := e.set(cal); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload re-reads the calendar file. On error the current calendar stays in
+// place.
+func (e *Engine) Reload() error {
+	var cal Calendar
+	if e.path != "" {
+		data, err := os.ReadFile(e.path)
+		if err != nil {
+			return fmt.Errorf("failed to read trading calendar: %v", err)
+		}
+		if err := json.Unmarshal(data, &cal); err != nil {
+			return fmt.Errorf("failed to parse trading calendar: %v", err)
+		}
+	}
+	return e.set(cal)
+}
+
+func (e *Engine) set(cal Calendar) error {
+	fallback, err := compile(cal.Default)
+	if err != nil {
+		return fmt.Errorf("default schedule: %v", err)
+	}
+	symbols := make(map[string]*schedule, len(cal.Symbols))
+	for symbol, s := range cal.Symbols {
+		compiled, err := compile(s)
+		if err != nil {
+			return fmt.Errorf("schedule of %s: %v", symbol, err)
+		}
+		symbols[strings.ToUpper(symbol)] = compiled
+	}
+
+	e.mu.Lock()
+	e.fallback, e.symbols = fallback, symbols
+	e.mu.Unlock()
+	return nil
+}
+
+// Session returns the symbol's session in progress at t, or the next one if
+// the market is closed. ok is false if no session is scheduled within a year.
+func (e *Engine) Session(symbol string, t time.Time) (Session, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	s, ok := e.symbols[symbol]
+	if !ok {
+		s = e.fallback
+	}
+	return s.session(t)
+}
+
+// IsOpen reports whether the symbol trades at t.
+func (e *Engine) IsOpen(symbol string, t time.Time) bool {
+	session, ok := e.Session(symbol, t)
+	return ok && session.Contains(t)
+}
@@ -0,0 +1,68 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	engine, err := NewEngineWithCalendar(Calendar{
+		Default: Schedule{
+			Timezone: "America/New_York",
+			Open:     "09:30",
+			Close:    "16:00",
+			Days:     []string{"mon", "tue", "wed", "thu", "fri"},
+			Holidays: []string{"2026-12-25"},
+			HalfDays: map[string]string{"2026-12-24": "13:00"},
+		},
+		Symbols: map[string]Schedule{"btcusd": {}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 12, day, hour, minute, 0, 0, ny) }
+
+	tests := []struct {
+		name      string
+		symbol    string
+		t         time.Time
+		open      bool
+		nextOpen  time.Time
+		nextClose time.Time
+	}{
+		{"in session", "ETHUSD", at(23, 10, 0), true, at(23, 9, 30), at(23, 16, 0)},
+		{"before the open", "ETHUSD", at(23, 9, 0), false, at(23, 9, 30), at(23, 16, 0)},
+		{"half day", "ETHUSD", at(24, 14, 0), false, at(28, 9, 30), at(28, 16, 0)},
+		{"holiday and weekend", "ETHUSD", at(25, 10, 0), false, at(28, 9, 30), at(28, 16, 0)},
+		{"own schedule", "BTCUSD", at(25, 10, 0), true, time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 26, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, ok := engine.Session(tt.symbol, tt.t)
+			if !ok {
+				t.Fatal("expected a session")
+			}
+			if session.Contains(tt.t) != tt.open || !session.Open.Equal(tt.nextOpen) || !session.Close.Equal(tt.nextClose) {
+				t.Errorf("unexpected session %v-%v, open %v", session.Open, session.Close, session.Contains(tt.t))
+			}
+		})
+	}
+}
+
+func TestScheduleRejectsBadConfig(t *testing.T) {
+	schedules := []Schedule{
+		{Timezone: "Mars/Olympus"},
+		{Open: "16:00", Close: "09:30"},
+		{Close: "25:00"},
+		{Days: []string{"someday"}},
+		{Holidays: []string{"25/12/2026"}},
+		{Open: "09:30", Close: "16:00", HalfDays: map[string]string{"2026-12-24": "17:00"}},
+	}
+	for _, s := range schedules {
+		if _, err := NewEngineWithCalendar(Calendar{Default: s}); err == nil {
+			t.Errorf("expected %+v to be rejected", s)
+		}
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"log"
 	"os"
 	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/matching"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 
@@ -22,6 +26,36 @@ type Config struct {
 	ClientSecret string // Machine secret for authentication
 	AuthUrl      string // URL for authentication
 	DataUrl      string // URL for data service
+	RiskRules    string // Path to the risk rules JSON file, empty for the default rules
+	FeeSchedule  string // Path to the fee schedule JSON file, empty to charge no fees
+	Calendar     string // Path to the trading calendar JSON file, empty to trade around the clock
+
+	DataTimeout          time.Duration // Deadline of a single data service request
+	DataRetries          int           // Retries of a failed data service request
+	DataBreakerThreshold int           // Consecutive data service failures that open the circuit
+	DataBreakerCooldown  time.Duration // How long the circuit stays open
+	DataFallback         string        // "reject" or "last_known" when the data service is down
+	DataMaxStaleness     time.Duration // Oldest prices the "last_known" fallback may use
+	DataStreamUrl        string        // URL of the data service price stream, empty to poll DataUrl instead
+	DataActivityUrl      string        // URL of the data service price activity by time of day, used to size VWAP slices
+	IdempotencyTTL       time.Duration // How long idempotency keys of trade submissions are kept
+	BuyingPowerCheck     bool          // Reject buys the user's available cash cannot pay for
+	AdminEmails          []string      // Users allowed to use the /admin endpoints
+	PaperTrading         bool          // Send every user's orders to paper trading
+	PaperDBPath          string        // Database of the paper trading book and ledger
+	PaperStartingCash    float64       // Cash a paper account starts with, in the default currency
+
+	VolatilityHaltPercent  float64       // Price move that halts all trading, zero to never halt automatically
+	VolatilityHaltWindow   time.Duration // Period the price move is measured over
+	VolatilityHaltDuration time.Duration // How long an automatic halt lasts
+
+	SelfMatchPolicy string        // matching.CancelNewest, CancelOldest or CancelBoth
+	RoundTripWindow time.Duration // Buying and selling at one price within it is reported as a round trip
+
+	WebhookTimeout  time.Duration // Deadline of a single webhook delivery
+	WebhookRetryMin time.Duration // Delay before retrying a failed delivery, doubled on every further failure
+	WebhookRetryMax time.Duration // Longest delay between retries
+	OutboxRetention time.Duration // How long delivered outbox events are kept
 }
 
 func Init() (*gorm.DB, *Config) {
@@ -44,13 +78,14 @@ func Load() *Config {
 	// Remaining logic stays the same
 
 	cfg := &Config{
-		DBPath:       "auth.db",  // Default SQLite DB path
-		Port:         ":8081",    // Default server port
-		JWTSecret:    "mysecret", // Default JWT secret key
-		ClientId:     "myclientid",
-		ClientSecret: "myclientsecret",
-		AuthUrl:      "http://localhost:8080/auth/token",  // Default authentication URL
-		DataUrl:      "http://localhost:8081/data/lowest", // Default data URL
+		DBPath:          "auth.db",  // Default SQLite DB path
+		Port:            ":8081",    // Default server port
+		JWTSecret:       "mysecret", // Default JWT secret key
+		ClientId:        "myclientid",
+		ClientSecret:    "myclientsecret",
+		AuthUrl:         "http://localhost:8080/auth/token",  // Default authentication URL
+		DataUrl:         "http://localhost:8081/data/lowest", // Default data URL
+		DataActivityUrl: "http://localhost:8081/data/activity",
 	}
 
 	// Override with environment variables if they exist
@@ -86,9 +121,111 @@ func Load() *Config {
 
 	}
 
+	if activityUrl := os.Getenv("DATA_ACTIVITY_URL"); activityUrl != "" {
+		cfg.DataActivityUrl = activityUrl
+	}
+
+	if riskRules := os.Getenv("RISK_RULES"); riskRules != "" {
+		cfg.RiskRules = riskRules
+	}
+
+	if feeSchedule := os.Getenv("FEE_SCHEDULE"); feeSchedule != "" {
+		cfg.FeeSchedule = feeSchedule
+	}
+
+	if calendar := os.Getenv("TRADING_CALENDAR"); calendar != "" {
+		cfg.Calendar = calendar
+	}
+
+	cfg.DataTimeout = durationEnv("DATA_TIMEOUT", 2*time.Second)
+	cfg.DataRetries = intEnv("DATA_RETRIES", 2)
+	cfg.DataBreakerThreshold = intEnv("DATA_BREAKER_THRESHOLD", 5)
+	cfg.DataBreakerCooldown = durationEnv("DATA_BREAKER_COOLDOWN", 30*time.Second)
+	cfg.DataFallback = "reject"
+	if fallback := os.Getenv("DATA_FALLBACK"); fallback != "" {
+		cfg.DataFallback = fallback
+	}
+	cfg.DataMaxStaleness = durationEnv("DATA_MAX_STALENESS", 5*time.Minute)
+	if streamUrl := os.Getenv("DATA_STREAM_URL"); streamUrl != "" {
+		cfg.DataStreamUrl = streamUrl
+	}
+	cfg.IdempotencyTTL = durationEnv("IDEMPOTENCY_TTL", 24*time.Hour)
+	cfg.BuyingPowerCheck = os.Getenv("BUYING_POWER_CHECK") != "false"
+	cfg.PaperTrading = os.Getenv("PAPER_TRADING") == "true"
+	cfg.PaperDBPath = "paper.db"
+	if paperDBPath := os.Getenv("PAPER_DB_PATH"); paperDBPath != "" {
+		cfg.PaperDBPath = paperDBPath
+	}
+	cfg.PaperStartingCash = floatEnv("PAPER_STARTING_CASH", 100000)
+	cfg.VolatilityHaltPercent = floatEnv("VOLATILITY_HALT_PERCENT", 0)
+	cfg.VolatilityHaltWindow = durationEnv("VOLATILITY_HALT_WINDOW", 5*time.Minute)
+	cfg.VolatilityHaltDuration = durationEnv("VOLATILITY_HALT_DURATION", 5*time.Minute)
+	cfg.SelfMatchPolicy = matching.CancelNewest
+	if policy := os.Getenv("SELF_MATCH_POLICY"); policy != "" {
+		if matching.IsSelfMatchPolicy(policy) {
+			cfg.SelfMatchPolicy = policy
+		} else {
+			log.Printf("Invalid SELF_MATCH_POLICY %q, using %s", policy, cfg.SelfMatchPolicy)
+		}
+	}
+	cfg.RoundTripWindow = durationEnv("SURVEILLANCE_ROUND_TRIP_WINDOW", time.Minute)
+	cfg.WebhookTimeout = durationEnv("WEBHOOK_TIMEOUT", 5*time.Second)
+	cfg.WebhookRetryMin = durationEnv("WEBHOOK_RETRY_MIN", time.Second)
+	cfg.WebhookRetryMax = durationEnv("WEBHOOK_RETRY_MAX", 10*time.Minute)
+	cfg.OutboxRetention = durationEnv("OUTBOX_RETENTION", 7*24*time.Hour)
+	if admins := os.Getenv("ADMIN_EMAILS"); admins != "" {
+		for _, admin := range strings.Split(admins, ",") {
+			if admin = strings.TrimSpace(admin); admin != "" {
+				cfg.AdminEmails = append(cfg.AdminEmails, admin)
+			}
+		}
+	}
+
 	return cfg
 }
 
+// durationEnv reads a duration such as "500ms" from the environment.
+func durationEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+// floatEnv reads a number from the environment.
+func floatEnv(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %.2f", key, v, def)
+		return def
+	}
+	return f
+}
+
+// intEnv reads an integer from the environment.
+func intEnv(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
 func InitDatabase(path string) *gorm.DB {
 	sqlDB, err := sql.Open("sqlite", path)
 	if err != nil {
@@ -100,8 +237,8 @@ func InitDatabase(path string) *gorm.DB {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Auto migrate User schema
-	err = db.AutoMigrate(&domain.Trade{})
+	// Auto migrate schemas
+	err = db.AutoMigrate(&domain.Trade{}, &domain.Execution{}, &domain.OrderEvent{}, &domain.IdempotencyKey{}, &domain.Position{}, &domain.JournalEntry{}, &domain.Posting{}, &domain.AccountSettings{}, &domain.AlgoOrder{}, &domain.AlgoSlice{}, &domain.OrderGroup{}, &domain.Halt{}, &domain.OutboxEvent{}, &domain.Webhook{})
 	if err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
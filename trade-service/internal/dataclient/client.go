@@ -0,0 +1,255 @@
+package dataclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"simpletrading/tradeservice/internal/auth"
+	"simpletrading/tradeservice/internal/domain"
+)
+
+// Fallback policies for when the Data Service cannot be reached.
+const (
+	FallbackReject    = "reject"     // Fail the call
+	FallbackLastKnown = "last_known" // Serve the last prices seen, if recent enough
+)
+
+var (
+	// ErrUnavailable is returned when no prices could be fetched and the
+	// fallback policy gave nothing to serve instead.
+	ErrUnavailable = errors.New("data service unavailable")
+	// errCircuitOpen is returned while the breaker fails calls fast.
+	errCircuitOpen = errors.New("circuit breaker open")
+)
+
+// Options tunes the client. Zero durations, thresholds and fallback take the
+// defaults; Retries may be zero to disable retrying.
+type Options struct {
+	ActivityURL      string        // The Data Service's /data/activity endpoint, empty if there is none
+	Timeout          time.Duration // Deadline of a single attempt
+	Retries          int           // Extra attempts after the first one fails
+	RetryBackoff     time.Duration // Upper bound of the first jittered backoff, doubled per retry
+	BreakerThreshold int           // Consecutive failures that open the circuit
+	BreakerCooldown  time.Duration // How long the circuit stays open
+	Fallback         string        // FallbackReject or FallbackLastKnown
+	MaxStaleness     time.Duration // Oldest last known prices FallbackLastKnown may serve
+}
+
+// DefaultOptions holds the defaults used for options left at zero.
+var DefaultOptions = Options{
+	Timeout:          2 * time.Second,
+	Retries:          2,
+	RetryBackoff:     100 * time.Millisecond,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+	Fallback:         FallbackReject,
+	MaxStaleness:     5 * time.Minute,
+}
+
+// Client is a typed client for the Data Service.
+type Client struct {
+	statsURL string
+	tokens   *auth.TokenSource
+	http     *http.Client
+	opts     Options
+	breaker  *breaker
+
+	mu        sync.Mutex
+	lastStats domain.PriceStats
+	lastAt    time.Time
+}
+
+// New creates a client that reads 24h prices from statsURL (the Data
+// Service's /data/lowest endpoint).
+func New(statsURL string, tokens *auth.TokenSource, opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultOptions.RetryBackoff
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = DefaultOptions.BreakerThreshold
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = DefaultOptions.BreakerCooldown
+	}
+	if opts.Fallback == "" {
+		opts.Fallback = DefaultOptions.Fallback
+	}
+	if opts.MaxStaleness <= 0 {
+		opts.MaxStaleness = DefaultOptions.MaxStaleness
+	}
+
+	return &Client{
+		statsURL: statsURL,
+		tokens:   tokens,
+		http:     &http.Client{},
+		opts:     opts,
+		breaker:  newBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+	}
+}
+
+// PriceStats returns the lowest, highest and last price of the last 24
+// hours. Each attempt is bounded by the client timeout and by ctx. If every
+// attempt fails, the fallback policy decides between an error wrapping
+// ErrUnavailable and the last known prices; the second result reports
+// whether the prices are such a stale fallback.
+func (c *Client) PriceStats(ctx context.Context) (domain.PriceStats, bool, error) {
+	var stats domain.PriceStats
+	err := c.get(ctx, c.statsURL, func(body []byte) error {
+		// The "lowest" key is required; "highest" and "last" are optional
+		var result map[string]float64
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("data decode failed: %v", err)
+		}
+		lowest, ok := result["lowest"]
+		if !ok {
+			return fmt.Errorf("missing 'lowest' value in the response")
+		}
+		stats = domain.PriceStats{Lowest: lowest, Highest: result["highest"], Last: result["last"]}
+		return nil
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		c.lastStats, c.lastAt = stats, time.Now()
+		return stats, false, nil
+	}
+
+	if c.opts.Fallback == FallbackLastKnown && !c.lastAt.IsZero() && time.Since(c.lastAt) <= c.opts.MaxStaleness {
+		log.Printf("Data service unavailable (%v), using prices from %s", err, c.lastAt.Format(time.RFC3339))
+		return c.lastStats, true, nil
+	}
+	return domain.PriceStats{}, false, fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+// PriceActivity returns how much the price moved by time of day over the
+// last days, in buckets of the given width; bucket 0 starts at midnight
+// UTC. There is no fallback: if every attempt fails the error wraps
+// ErrUnavailable.
+func (c *Client) PriceActivity(ctx context.Context, days int, bucket time.Duration) (map[int]float64, error) {
+	if c.opts.ActivityURL == "" {
+		return nil, fmt.Errorf("%w: no activity URL configured", ErrUnavailable)
+	}
+	query := url.Values{"days": {fmt.Sprint(days)}, "bucket": {bucket.String()}}
+
+	var activity map[int]float64
+	err := c.get(ctx, c.opts.ActivityURL+"?"+query.Encode(), func(body []byte) error {
+		var result struct {
+			Activity map[int]float64 `json:"activity"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("data decode failed: %v", err)
+		}
+		activity = result.Activity
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return activity, nil
+}
+
+// get performs an idempotent GET with retries, passing the body of the first
+// successful response to decode.
+func (c *Client) get(ctx context.Context, url string, decode func([]byte) error) error {
+	var err error
+	for attempt := 0; attempt <= c.opts.Retries; attempt++ {
+		if attempt > 0 {
+			if waitErr := c.backoff(ctx, attempt); waitErr != nil {
+				return err
+			}
+		}
+
+		if !c.breaker.allow() {
+			return errCircuitOpen
+		}
+
+		var body []byte
+		var retry bool
+		body, retry, err = c.attempt(ctx, url)
+		if err == nil {
+			err = decode(body)
+		}
+		// Only failures worth retrying say the service is down; a 4xx or a
+		// bad body means it answered
+		c.breaker.record(err == nil || !retry)
+
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// attempt sends one request. It reports whether a failure is worth retrying.
+func (c *Client) attempt(ctx context.Context, url string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	// A 401 means the cached machine token went stale, so it is dropped and
+	// the request is sent once more with a fresh one
+	for refreshed := false; ; refreshed = true {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, true, fmt.Errorf("auth failed: %v", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, true, fmt.Errorf("data request failed: %v", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && !refreshed:
+			c.tokens.Invalidate(token)
+			continue
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			return nil, true, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		case resp.StatusCode != http.StatusOK:
+			return nil, false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		case err != nil:
+			return nil, true, fmt.Errorf("failed to read response body: %v", err)
+		}
+		return body, false, nil
+	}
+}
+
+// backoff sleeps for a random duration up to RetryBackoff doubled per retry
+// ("full jitter"), or until ctx is done.
+func (c *Client) backoff(ctx context.Context, attempt int) error {
+	limit := c.opts.RetryBackoff << (attempt - 1)
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(limit)) + 1))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,148 @@
+package dataclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"simpletrading/tradeservice/internal/auth"
+)
+
+// setupServices starts a fake auth service and a data service whose answers
+// are decided by respond, which gets the 1-based call number.
+func setupServices(t *testing.T, respond func(call int32, w http.ResponseWriter)) (*auth.TokenSource, string, *int32) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "test-token"})
+	}))
+	t.Cleanup(authServer.Close)
+
+	var calls int32
+	dataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(atomic.AddInt32(&calls, 1), w)
+	}))
+	t.Cleanup(dataServer.Close)
+
+	return auth.NewTokenSource(authServer.URL, "id", "secret"), dataServer.URL, &calls
+}
+
+func prices(w http.ResponseWriter) {
+	json.NewEncoder(w).Encode(map[string]float64{"lowest": 80, "highest": 120, "last": 100})
+}
+
+func TestPriceStatsRetriesServerErrors(t *testing.T) {
+	tokens, url, calls := setupServices(t, func(call int32, w http.ResponseWriter) {
+		if call < 3 {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		prices(w)
+	})
+	client := New(url, tokens, Options{Retries: 2, RetryBackoff: time.Millisecond})
+
+	stats, stale, err := client.PriceStats(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stale || stats.Lowest != 80 || stats.Highest != 120 || stats.Last != 100 {
+		t.Errorf("unexpected stats: %+v (stale %v)", stats, stale)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 calls, got %d", *calls)
+	}
+}
+
+func TestPriceStatsDoesNotRetryClientErrors(t *testing.T) {
+	tokens, url, calls := setupServices(t, func(call int32, w http.ResponseWriter) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+	client := New(url, tokens, Options{Retries: 2, RetryBackoff: time.Millisecond})
+
+	if _, _, err := client.PriceStats(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("expected a single call, got %d", *calls)
+	}
+}
+
+func TestPriceStatsTimesOut(t *testing.T) {
+	tokens, url, _ := setupServices(t, func(call int32, w http.ResponseWriter) {
+		time.Sleep(200 * time.Millisecond)
+		prices(w)
+	})
+	client := New(url, tokens, Options{Timeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	if _, _, err := client.PriceStats(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("expected the call to give up early, took %s", elapsed)
+	}
+}
+
+func TestPriceStatsTimesOutOnStalledAuth(t *testing.T) {
+	release := make(chan struct{})
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(authServer.Close)
+	t.Cleanup(func() { close(release) })
+	_, url, calls := setupServices(t, func(call int32, w http.ResponseWriter) { prices(w) })
+	tokens := auth.NewTokenSource(authServer.URL, "id", "secret")
+	client := New(url, tokens, Options{Timeout: 20 * time.Millisecond, Retries: 1, RetryBackoff: time.Millisecond})
+
+	start := time.Now()
+	if _, _, err := client.PriceStats(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("expected the call to give up early, took %s", elapsed)
+	}
+	if *calls != 0 {
+		t.Errorf("expected no data request without a token, got %d", *calls)
+	}
+}
+
+func TestCircuitBreakerFailsFast(t *testing.T) {
+	tokens, url, calls := setupServices(t, func(call int32, w http.ResponseWriter) {
+		http.Error(w, "boom", http.StatusServiceUnavailable)
+	})
+	client := New(url, tokens, Options{BreakerThreshold: 2, BreakerCooldown: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		client.PriceStats(context.Background())
+	}
+	if *calls != 2 {
+		t.Errorf("expected the breaker to stop calls after 2 failures, got %d calls", *calls)
+	}
+}
+
+func TestLastKnownFallback(t *testing.T) {
+	tokens, url, _ := setupServices(t, func(call int32, w http.ResponseWriter) {
+		if call == 1 {
+			prices(w)
+			return
+		}
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	client := New(url, tokens, Options{Fallback: FallbackLastKnown, MaxStaleness: time.Minute})
+	client.PriceStats(context.Background())
+
+	stats, stale, err := client.PriceStats(context.Background())
+	if err != nil || !stale || stats.Lowest != 80 {
+		t.Fatalf("expected stale fallback prices, got %+v %v %v", stats, stale, err)
+	}
+
+	// Past the staleness bound the fallback gives up
+	client.lastAt = time.Now().Add(-2 * time.Minute)
+	if _, _, err := client.PriceStats(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable past the staleness bound, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type movementRequest struct {
+	Currency  string  `json:"currency"`
+	Amount    float64 `json:"amount"`
+	Reference string  `json:"reference"`
+}
+
+// GetBalances handles the GET /accounts endpoint
+func (h *Handler) GetBalances(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	balances, err := uc.GetBalances(email)
+	if err != nil {
+		http.Error(w, "Failed to get balances", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"balances": balances})
+}
+
+// ListJournal handles the GET /accounts/journal endpoint
+func (h *Handler) ListJournal(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	entries, err := uc.ListJournal(email)
+	if err != nil {
+		http.Error(w, "Failed to get journal", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+type modeRequest struct {
+	Mode string `json:"mode"`
+}
+
+// GetTradingMode handles the GET /accounts/mode endpoint
+func (h *Handler) GetTradingMode(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mode, err := h.uc.TradingMode(email)
+	if err != nil {
+		http.Error(w, "Failed to get trading mode", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"mode": mode})
+}
+
+// SetTradingMode handles the PUT /accounts/mode endpoint
+func (h *Handler) SetTradingMode(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req modeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.uc.SetTradingMode(h.paper, email, req.Mode); err != nil {
+		writeError(w, err, "Failed to set trading mode")
+		return
+	}
+
+	// The global paper trading switch wins over the account's setting
+	mode, err := h.uc.TradingMode(email)
+	if err != nil {
+		http.Error(w, "Failed to get trading mode", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"mode": mode})
+}
+
+// Deposit handles the POST /admin/accounts/{user}/deposits endpoint
+func (h *Handler) Deposit(w http.ResponseWriter, r *http.Request) {
+	var req movementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.uc.Deposit(r.PathValue("user"), req.Currency, req.Amount, req.Reference)
+	if err != nil {
+		writeError(w, err, "Failed to deposit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// Withdraw handles the POST /admin/accounts/{user}/withdrawals endpoint
+func (h *Handler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	var req movementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.uc.Withdraw(r.PathValue("user"), req.Currency, req.Amount, req.Reference)
+	if err != nil {
+		writeError(w, err, "Failed to withdraw")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// CheckLedger handles the GET /admin/ledger/check endpoint. It answers 500
+// if the ledger is inconsistent, so monitoring can alert on the status.
+func (h *Handler) CheckLedger(w http.ResponseWriter, r *http.Request) {
+	check, err := h.uc.CheckLedger()
+	if err != nil {
+		http.Error(w, "Failed to check ledger", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !check.Consistent {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(check)
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+type algoOrderRequest struct {
+	Symbol   string     `json:"symbol"`
+	Side     string     `json:"side"`
+	Algo     string     `json:"algo"`
+	Quantity float64    `json:"quantity"`
+	Price    float64    `json:"price"`
+	StartAt  *time.Time `json:"start_at"`
+	Duration string     `json:"duration"` // e.g. "30m"
+	Slices   int        `json:"slices"`
+}
+
+// SubmitAlgoOrder handles the POST /algo-orders endpoint
+func (h *Handler) SubmitAlgoOrder(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req algoOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid duration %q", req.Duration), http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	algoReq := usecase.AlgoRequest{
+		Symbol:   req.Symbol,
+		Side:     req.Side,
+		Algo:     req.Algo,
+		Quantity: req.Quantity,
+		Price:    req.Price,
+		Duration: duration,
+		Slices:   req.Slices,
+	}
+	if req.StartAt != nil {
+		algoReq.StartAt = *req.StartAt
+	}
+
+	order, err := uc.SubmitAlgoOrder(r.Context(), email, algoReq)
+	if err != nil {
+		writeError(w, err, "Failed to submit algo order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// ListAlgoOrders handles the GET /algo-orders endpoint
+func (h *Handler) ListAlgoOrders(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	orders, err := uc.ListAlgoOrders(email)
+	if err != nil {
+		http.Error(w, "Failed to get algo orders", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+// GetAlgoOrder handles the GET /algo-orders/{id} endpoint
+func (h *Handler) GetAlgoOrder(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid algo order id", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	order, err := uc.GetAlgoOrder(email, uint(id))
+	if err != nil {
+		writeError(w, err, "Failed to get algo order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// CancelAlgoOrder handles the DELETE /algo-orders/{id} endpoint
+func (h *Handler) CancelAlgoOrder(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid algo order id", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	order, err := uc.CancelAlgoOrder(email, uint(id))
+	if err != nil {
+		writeError(w, err, "Failed to cancel algo order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
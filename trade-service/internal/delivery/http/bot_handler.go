@@ -0,0 +1,79 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"simpletrading/tradeservice/internal/bots"
+)
+
+type startBotRequest struct {
+	Name     string          `json:"name"`
+	Strategy string          `json:"strategy"`
+	Params   json.RawMessage `json:"params"`
+	Interval string          `json:"interval"` // e.g. "30s"; empty runs on every streamed price
+	Mode     string          `json:"mode"`
+}
+
+// ListBots handles the GET /admin/bots endpoint
+func (h *Handler) ListBots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bots":       h.bots.List(r.Context()),
+		"strategies": bots.Strategies(),
+	})
+}
+
+// StartBot handles the POST /admin/bots endpoint
+func (h *Handler) StartBot(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req startBotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var interval time.Duration
+	if req.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(req.Interval); err != nil || interval <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid interval %q", req.Interval), http.StatusBadRequest)
+			return
+		}
+	}
+
+	status, err := h.bots.Start(email, bots.StartRequest{
+		Name:     req.Name,
+		Strategy: req.Strategy,
+		Params:   req.Params,
+		Interval: interval,
+		Mode:     req.Mode,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to start bot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(status)
+}
+
+// StopBot handles the DELETE /admin/bots/{name} endpoint
+func (h *Handler) StopBot(w http.ResponseWriter, r *http.Request) {
+	status, err := h.bots.Stop(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeError(w, err, "Failed to stop bot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+type orderGroupRequest struct {
+	Type string `json:"type"` // "bracket" or "oco"
+
+	// Bracket
+	Entry         *tradeRequest `json:"entry"`
+	TakeProfit    float64       `json:"take_profit"`
+	StopLoss      float64       `json:"stop_loss"`
+	StopLossLimit float64       `json:"stop_loss_limit"`
+
+	// OCO
+	Orders []tradeRequest `json:"orders"`
+}
+
+// PlaceOrderGroup handles the POST /order-groups endpoint
+func (h *Handler) PlaceOrderGroup(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req orderGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Type == domain.GroupTypeBracket && req.Entry == nil {
+		http.Error(w, "A bracket needs an entry order", http.StatusBadRequest)
+		return
+	}
+	if req.Type != domain.GroupTypeBracket && req.Type != domain.GroupTypeOCO {
+		http.Error(w, fmt.Sprintf("Invalid order group type %q", req.Type), http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	var group *domain.OrderGroup
+	var err error
+	if req.Type == domain.GroupTypeBracket {
+		group, err = uc.PlaceBracket(r.Context(), email, usecase.BracketRequest{
+			Entry:         req.Entry.trade(),
+			TakeProfit:    req.TakeProfit,
+			StopLoss:      req.StopLoss,
+			StopLossLimit: req.StopLossLimit,
+		})
+	} else {
+		legs := make([]domain.Trade, len(req.Orders))
+		for i, o := range req.Orders {
+			legs[i] = o.trade()
+		}
+		group, err = uc.PlaceOCO(r.Context(), email, legs)
+	}
+	if err != nil {
+		writeError(w, err, "Failed to place order group")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(group)
+}
+
+// ListOrderGroups handles the GET /order-groups endpoint
+func (h *Handler) ListOrderGroups(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	groups, err := uc.ListOrderGroups(email)
+	if err != nil {
+		http.Error(w, "Failed to get order groups", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// GetOrderGroup handles the GET /order-groups/{id} endpoint
+func (h *Handler) GetOrderGroup(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order group id", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	group, err := uc.GetOrderGroup(email, uint(id))
+	if err != nil {
+		writeError(w, err, "Failed to get order group")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// CancelOrderGroup handles the DELETE /order-groups/{id} endpoint
+func (h *Handler) CancelOrderGroup(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order group id", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	group, err := uc.CancelOrderGroup(email, uint(id))
+	if err != nil {
+		writeError(w, err, "Failed to cancel order group")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
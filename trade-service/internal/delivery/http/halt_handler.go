@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+type haltRequest struct {
+	Scope        string `json:"scope"`  // "global", "symbol" or "user"
+	Target       string `json:"target"` // Symbol or user; empty for a global halt
+	Reason       string `json:"reason"`
+	Duration     string `json:"duration"`      // e.g. "15m"; empty lasts until lifted
+	CancelOrders bool   `json:"cancel_orders"` // Cancel the open orders the halt covers
+}
+
+type haltResponse struct {
+	*domain.Halt
+	CancelledOrders int `json:"cancelled_orders"`
+}
+
+// ListHalts handles the GET /admin/halts endpoint
+func (h *Handler) ListHalts(w http.ResponseWriter, r *http.Request) {
+	halts, err := h.halts.ListActive()
+	if err != nil {
+		writeError(w, err, "Failed to list halts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(halts)
+}
+
+// HaltTrading handles the POST /admin/halts endpoint
+func (h *Handler) HaltTrading(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req haltRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil || duration <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid duration %q", req.Duration), http.StatusBadRequest)
+			return
+		}
+	}
+
+	halt, err := h.halts.Halt(email, usecase.HaltRequest{
+		Scope:    req.Scope,
+		Target:   req.Target,
+		Reason:   req.Reason,
+		Duration: duration,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to halt trading")
+		return
+	}
+
+	// The halt holds for live and paper orders alike
+	resp := haltResponse{Halt: halt}
+	if req.CancelOrders {
+		for _, uc := range []*usecase.TradeUsecase{h.uc, h.paper} {
+			cancelled, err := uc.CancelHaltedOrders(halt)
+			resp.CancelledOrders += cancelled
+			if err != nil {
+				writeError(w, err, "Halted trading but failed to cancel orders")
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// LiftHalt handles the DELETE /admin/halts/{id} endpoint
+func (h *Handler) LiftHalt(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid halt id", http.StatusBadRequest)
+		return
+	}
+
+	halt, err := h.halts.Lift(email, uint(id))
+	if err != nil {
+		writeError(w, err, "Failed to lift halt")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(halt)
+}
@@ -2,47 +2,338 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
+	"simpletrading/tradeservice/internal/bots"
+	"simpletrading/tradeservice/internal/domain"
 	"simpletrading/tradeservice/internal/usecase"
 )
 
 type Handler struct {
-	uc *usecase.TradeUsecase
+	uc      *usecase.TradeUsecase
+	paper   *usecase.TradeUsecase
+	idem    *usecase.IdempotencyUsecase
+	halts   *usecase.HaltUsecase
+	reports *usecase.ReportUsecase
+	outbox  *usecase.OutboxUsecase
+	bots    *bots.Runner
 }
 
-func NewHandler(uc *usecase.TradeUsecase) *Handler {
-	return &Handler{uc: uc}
+// NewHandler creates the handler. uc serves live trading and the admin
+// endpoints, paper the users in paper trading mode. Reports cover live
+// trading only, and so do the webhooks of outbox.
+func NewHandler(uc, paper *usecase.TradeUsecase, idem *usecase.IdempotencyUsecase, halts *usecase.HaltUsecase, reports *usecase.ReportUsecase, outbox *usecase.OutboxUsecase, runner *bots.Runner) *Handler {
+	return &Handler{uc: uc, paper: paper, idem: idem, halts: halts, reports: reports, outbox: outbox, bots: runner}
 }
 
 func (h *Handler) Router() http.Handler {
 	mux := http.NewServeMux()
 
-	mux.Handle("/trade", JWTMiddleware(http.HandlerFunc(h.PlaceTrade)))
+	mux.Handle("POST /trade", JWTMiddleware(h.idempotent(http.HandlerFunc(h.PlaceTrade))))
+	mux.Handle("POST /trade/preview", JWTMiddleware(http.HandlerFunc(h.PreviewTrade)))
+	mux.Handle("GET /trades", JWTMiddleware(http.HandlerFunc(h.ListTrades)))
+	mux.Handle("GET /trades/{id}", JWTMiddleware(http.HandlerFunc(h.GetTrade)))
+	mux.Handle("GET /trades/{id}/executions", JWTMiddleware(http.HandlerFunc(h.ListExecutions)))
+	mux.Handle("DELETE /orders/{id}", JWTMiddleware(http.HandlerFunc(h.CancelOrder)))
+	mux.Handle("PATCH /orders/{id}", JWTMiddleware(http.HandlerFunc(h.AmendOrder)))
+	mux.Handle("GET /orders/{id}/events", JWTMiddleware(http.HandlerFunc(h.ListOrderEvents)))
+	mux.Handle("POST /algo-orders", JWTMiddleware(h.idempotent(http.HandlerFunc(h.SubmitAlgoOrder))))
+	mux.Handle("GET /algo-orders", JWTMiddleware(http.HandlerFunc(h.ListAlgoOrders)))
+	mux.Handle("GET /algo-orders/{id}", JWTMiddleware(http.HandlerFunc(h.GetAlgoOrder)))
+	mux.Handle("DELETE /algo-orders/{id}", JWTMiddleware(http.HandlerFunc(h.CancelAlgoOrder)))
+	mux.Handle("POST /order-groups", JWTMiddleware(h.idempotent(http.HandlerFunc(h.PlaceOrderGroup))))
+	mux.Handle("GET /order-groups", JWTMiddleware(http.HandlerFunc(h.ListOrderGroups)))
+	mux.Handle("GET /order-groups/{id}", JWTMiddleware(http.HandlerFunc(h.GetOrderGroup)))
+	mux.Handle("DELETE /order-groups/{id}", JWTMiddleware(http.HandlerFunc(h.CancelOrderGroup)))
+	mux.Handle("GET /portfolio", JWTMiddleware(http.HandlerFunc(h.GetPortfolio)))
+	mux.Handle("GET /portfolio/{symbol}", JWTMiddleware(http.HandlerFunc(h.GetPosition)))
+	mux.Handle("GET /accounts", JWTMiddleware(http.HandlerFunc(h.GetBalances)))
+	mux.Handle("GET /accounts/journal", JWTMiddleware(http.HandlerFunc(h.ListJournal)))
+	mux.Handle("GET /accounts/mode", JWTMiddleware(http.HandlerFunc(h.GetTradingMode)))
+	mux.Handle("PUT /accounts/mode", JWTMiddleware(http.HandlerFunc(h.SetTradingMode)))
+	mux.Handle("GET /reports/daily", JWTMiddleware(http.HandlerFunc(h.GetDailyReport)))
+	mux.Handle("POST /admin/accounts/{user}/deposits", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.Deposit))))
+	mux.Handle("POST /admin/accounts/{user}/withdrawals", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.Withdraw))))
+	mux.Handle("GET /admin/ledger/check", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.CheckLedger))))
+	mux.Handle("GET /admin/bots", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.ListBots))))
+	mux.Handle("POST /admin/bots", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.StartBot))))
+	mux.Handle("DELETE /admin/bots/{name}", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.StopBot))))
+	mux.Handle("GET /admin/surveillance", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.GetSurveillanceReport))))
+	mux.Handle("GET /admin/halts", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.ListHalts))))
+	mux.Handle("POST /admin/halts", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.HaltTrading))))
+	mux.Handle("DELETE /admin/halts/{id}", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.LiftHalt))))
+	mux.Handle("GET /admin/webhooks", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.ListWebhooks))))
+	mux.Handle("POST /admin/webhooks", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.RegisterWebhook))))
+	mux.Handle("DELETE /admin/webhooks/{id}", JWTMiddleware(h.adminOnly(http.HandlerFunc(h.DeleteWebhook))))
 	return mux
 }
 
+// tradeUsecase picks the live or paper usecase for the user's trading mode.
+// Paper responses carry a "Trading-Mode: paper" header. On error it answers
+// the request itself and returns false.
+func (h *Handler) tradeUsecase(w http.ResponseWriter, email string) (*usecase.TradeUsecase, bool) {
+	mode, err := h.uc.TradingMode(email)
+	if err != nil {
+		http.Error(w, "Failed to get trading mode", http.StatusInternalServerError)
+		return nil, false
+	}
+	if mode != domain.TradingModePaper {
+		return h.uc, true
+	}
+
+	if err := h.paper.FundPaperAccount(email); err != nil {
+		http.Error(w, "Failed to set up paper account", http.StatusInternalServerError)
+		return nil, false
+	}
+	w.Header().Set("Trading-Mode", domain.TradingModePaper)
+	return h.paper, true
+}
+
 type tradeRequest struct {
-	Price float64 `json:"price"`
+	Symbol       string  `json:"symbol"`
+	Side         string  `json:"side"`
+	Type         string  `json:"type"`
+	TimeInForce  string  `json:"time_in_force"`
+	Quantity     float64 `json:"quantity"`
+	Price        float64 `json:"price"`
+	StopPrice    float64 `json:"stop_price"`
+	TrailAmount  float64 `json:"trail_amount"`
+	TrailPercent float64 `json:"trail_percent"`
+}
+
+func (req tradeRequest) trade() domain.Trade {
+	return domain.Trade{
+		Symbol:       req.Symbol,
+		Side:         req.Side,
+		Type:         req.Type,
+		TimeInForce:  req.TimeInForce,
+		Quantity:     req.Quantity,
+		Price:        req.Price,
+		StopPrice:    req.StopPrice,
+		TrailAmount:  req.TrailAmount,
+		TrailPercent: req.TrailPercent,
+	}
 }
 
 // PlaceTrade handles placing a new trade
 func (h *Handler) PlaceTrade(w http.ResponseWriter, r *http.Request) {
-	amountStr := r.URL.Query().Get("amount")
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req tradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	trade, executions, err := uc.PlaceTrade(r.Context(), email, req.trade())
+	if err != nil {
+		writeError(w, err, "Failed to place trade")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "trade accepted",
+		"mode":       uc.Mode(),
+		"trade":      trade,
+		"executions": executions,
+	})
+}
+
+// PreviewTrade handles the POST /trade/preview endpoint. It answers what
+// placing the order would do, including why it would be rejected, without
+// placing it.
+func (h *Handler) PreviewTrade(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req tradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	preview, err := uc.PreviewTrade(r.Context(), email, req.trade())
+	if err != nil {
+		writeError(w, err, "Failed to preview trade")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+// ListTrades handles the GET /trades endpoint
+func (h *Handler) ListTrades(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseTradeFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	trades, next, err := uc.ListTrades(email, filter)
+	if err != nil {
+		http.Error(w, "Failed to get trades", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{"trades": trades}
+	if next > 0 {
+		resp["next_cursor"] = strconv.FormatUint(uint64(next), 10)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetTrade handles the GET /trades/{id} endpoint
+func (h *Handler) GetTrade(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		http.Error(w, "Invalid trade id", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
 		return
 	}
 
-	err = h.uc.PlaceTrade(amount)
+	trade, err := uc.GetTrade(email, uint(id))
+	if errors.Is(err, usecase.ErrTradeNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+		http.Error(w, "Failed to get trade", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trade)
+}
+
+// ListExecutions handles the GET /trades/{id}/executions endpoint
+func (h *Handler) ListExecutions(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"status": "trade accepted"})
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trade id", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	executions, err := uc.ListExecutions(email, uint(id))
+	if errors.Is(err, usecase.ErrTradeNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get executions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(executions)
+}
+
+// parseTradeFilter reads the optional from, to, min_price, max_price, cursor
+// and limit query parameters.
+func parseTradeFilter(q url.Values) (domain.TradeFilter, error) {
+	var filter domain.TradeFilter
+
+	if v := q.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from: must be RFC3339")
+		}
+		filter.From = from
+	}
+	if v := q.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to: must be RFC3339")
+		}
+		filter.To = to
+	}
+	if v := q.Get("min_price"); v != "" {
+		minPrice, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid min_price")
+		}
+		filter.MinPrice = &minPrice
+	}
+	if v := q.Get("max_price"); v != "" {
+		maxPrice, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid max_price")
+		}
+		filter.MaxPrice = &maxPrice
+	}
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor")
+		}
+		filter.Cursor = uint(cursor)
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return filter, fmt.Errorf("invalid limit")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
 }
 
 // // getLowestPriceFromDataService fetches the lowest price from Data Service API
@@ -1,12 +1,20 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"os"
 	"strings"
 
+	"simpletrading/tradeservice/internal/usecase"
+
 	"github.com/golang-jwt/jwt"
 )
 
@@ -70,3 +78,103 @@ func GetUserEmailFromContext(ctx context.Context) (string, bool) {
 	email, ok := ctx.Value(userContextKey).(string)
 	return email, ok
 }
+
+// adminOnly lets only the configured admins through. It must run after
+// JWTMiddleware.
+func (h *Handler) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := GetUserEmailFromContext(r.Context())
+		if !ok || !h.uc.IsAdmin(email) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// idempotent answers a request retried with the same Idempotency-Key header
+// with the response to the first one instead of handling it again. It must
+// run after JWTMiddleware, as keys belong to a user.
+func (h *Handler) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		email, ok := GetUserEmailFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		record, err := h.idem.Begin(email, key, hex.EncodeToString(sum[:]))
+		switch {
+		case errors.Is(err, usecase.ErrInvalidIdempotencyKey):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, usecase.ErrIdempotencyKeyReused):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, usecase.ErrRequestInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "Failed to check idempotency key", http.StatusInternalServerError)
+			return
+		}
+
+		// Replay the stored response
+		if record.StatusCode != 0 {
+			w.Header().Set("Content-Type", record.ContentType)
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write(record.Response)
+			return
+		}
+
+		// A handler that panics answers nothing worth keeping, so the key is
+		// released for a retry rather than left in progress until it expires
+		defer func() {
+			if p := recover(); p != nil {
+				if err := h.idem.Finish(record, http.StatusInternalServerError, "", nil); err != nil {
+					log.Println("Failed to release idempotency key:", err)
+				}
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if err := h.idem.Finish(record, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+			log.Println("Failed to store idempotent response:", err)
+		}
+	})
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
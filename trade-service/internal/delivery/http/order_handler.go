@@ -0,0 +1,167 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"simpletrading/tradeservice/internal/bots"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+type amendRequest struct {
+	Price     *float64 `json:"price"`
+	StopPrice *float64 `json:"stop_price"`
+	Quantity  *float64 `json:"quantity"`
+}
+
+// CancelOrder handles the DELETE /orders/{id} endpoint
+func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order id", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	trade, err := uc.CancelOrder(email, uint(id))
+	if err != nil {
+		writeError(w, err, "Failed to cancel order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trade)
+}
+
+// AmendOrder handles the PATCH /orders/{id} endpoint
+func (h *Handler) AmendOrder(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order id", http.StatusBadRequest)
+		return
+	}
+
+	var req amendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	trade, executions, err := uc.AmendOrder(r.Context(), email, uint(id), domain.TradeAmendment{
+		Price:     req.Price,
+		StopPrice: req.StopPrice,
+		Quantity:  req.Quantity,
+	})
+	if err != nil {
+		writeError(w, err, "Failed to amend order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"trade":      trade,
+		"executions": executions,
+	})
+}
+
+// ListOrderEvents handles the GET /orders/{id}/events endpoint
+func (h *Handler) ListOrderEvents(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order id", http.StatusBadRequest)
+		return
+	}
+
+	uc, ok := h.tradeUsecase(w, email)
+	if !ok {
+		return
+	}
+
+	events, err := uc.ListOrderEvents(email, uint(id))
+	if err != nil {
+		writeError(w, err, "Failed to get order events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// writeError answers with the status code matching a usecase error. Anything
+// unexpected is reported as an internal error with the given message.
+func writeError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, usecase.ErrTradeNotFound), errors.Is(err, usecase.ErrAlgoOrderNotFound),
+		errors.Is(err, usecase.ErrOrderGroupNotFound), errors.Is(err, usecase.ErrHaltNotFound),
+		errors.Is(err, usecase.ErrWebhookNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrInvalidTrade):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrTradeRejected):
+		writeRejection(w, err)
+	case errors.Is(err, usecase.ErrInvalidTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usecase.ErrMarketClosed), errors.Is(err, usecase.ErrTradingHalted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usecase.ErrMarketDataUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, usecase.ErrInvalidAmount), errors.Is(err, usecase.ErrInvalidTradingMode),
+		errors.Is(err, usecase.ErrInvalidHalt), errors.Is(err, usecase.ErrInvalidWebhook):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrInsufficientFunds):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, bots.ErrInvalidBot):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, bots.ErrBotNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, bots.ErrBotRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+// writeRejection answers a rejected order with the IDs of the risk rules it
+// broke, so clients need not parse the message.
+func writeRejection(w http.ResponseWriter, err error) {
+	resp := map[string]interface{}{"error": err.Error(), "rule_ids": []string{}}
+	var rejection *usecase.RejectionError
+	if errors.As(err, &rejection) {
+		resp["rule_ids"] = rejection.RuleIDs()
+		resp["violations"] = rejection.Violations
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"simpletrading/tradeservice/internal/usecase"
+)
+
+// GetDailyReport handles the GET /reports/daily endpoint. date is a UTC day
+// "2006-01-02", today by default, and format is "json" (the default) or
+// "csv". Admins get every user's statement and the firm-wide summary, other
+// users their own statement.
+func (h *Handler) GetDailyReport(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	date := time.Now().UTC()
+	if v := r.URL.Query().Get("date"); v != "" {
+		var err error
+		if date, err = time.Parse(time.DateOnly, v); err != nil {
+			http.Error(w, "invalid date: must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "invalid format: must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	userID := email
+	if h.uc.IsAdmin(email) {
+		userID = ""
+	}
+	report, err := h.reports.DailyReport(date, userID)
+	if err != nil {
+		writeError(w, err, "Failed to build daily report")
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"daily-%s.csv\"", report.Date))
+		usecase.WriteDailyReportCSV(w, report)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
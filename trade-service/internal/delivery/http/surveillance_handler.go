@@ -0,0 +1,39 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// GetSurveillanceReport handles the GET /admin/surveillance endpoint. The
+// period is given by the RFC3339 from and to parameters and defaults to the
+// last 24 hours.
+func (h *Handler) GetSurveillanceReport(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseTradeFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to := filter.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := filter.From
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.uc.SurveillanceReport(from, to)
+	if err != nil {
+		writeError(w, err, "Failed to build surveillance report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
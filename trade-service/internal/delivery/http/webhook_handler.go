@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"simpletrading/tradeservice/internal/domain"
+)
+
+type webhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"` // Empty to generate one
+}
+
+// webhookResponse shows the secret, which is only ever returned when the
+// webhook is registered.
+type webhookResponse struct {
+	*domain.Webhook
+	Secret string `json:"secret"`
+}
+
+// ListWebhooks handles the GET /admin/webhooks endpoint
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.outbox.ListWebhooks()
+	if err != nil {
+		writeError(w, err, "Failed to list webhooks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// RegisterWebhook handles the POST /admin/webhooks endpoint
+func (h *Handler) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	email, ok := GetUserEmailFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.outbox.RegisterWebhook(email, req.URL, req.Secret)
+	if err != nil {
+		writeError(w, err, "Failed to register webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhookResponse{Webhook: webhook, Secret: webhook.Secret})
+}
+
+// DeleteWebhook handles the DELETE /admin/webhooks/{id} endpoint
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.outbox.DeleteWebhook(uint(id))
+	if err != nil {
+		writeError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// Execution algorithms of parent orders: TWAP slices the quantity evenly
+// over the window, VWAP in proportion to the volume usually traded at each
+// time of day.
+const (
+	AlgoTWAP = "twap"
+	AlgoVWAP = "vwap"
+)
+
+const (
+	AlgoStatusWorking   = "working"
+	AlgoStatusCompleted = "completed" // The whole quantity filled
+	AlgoStatusExpired   = "expired"   // The window ended before the whole quantity filled
+	AlgoStatusCancelled = "cancelled"
+)
+
+const (
+	SliceStatusPending = "pending"
+	SliceStatusSent    = "sent"    // Placed as a child order
+	SliceStatusFailed  = "failed"  // The child order was refused, see Error
+	SliceStatusSkipped = "skipped" // Nothing was left to send, or the parent ended first
+)
+
+// AlgoOrder is a parent order executed as a schedule of child orders. The
+// children are immediate-or-cancel orders, limited to Price if it is set,
+// and each goes through the same checks as an order sent on its own.
+type AlgoOrder struct {
+	ID             uint        `gorm:"primaryKey" json:"id"`
+	UserID         string      `gorm:"not null;index" json:"user_id"`
+	Symbol         string      `gorm:"not null" json:"symbol"`
+	Side           string      `gorm:"not null" json:"side"`
+	Algo           string      `gorm:"not null" json:"algo"`
+	Quantity       float64     `gorm:"not null" json:"quantity"`
+	FilledQuantity float64     `gorm:"not null;default:0" json:"filled_quantity"`
+	Price          float64     `gorm:"not null;default:0" json:"price,omitempty"` // Limit price of the children, zero for market children
+	StartAt        time.Time   `gorm:"not null" json:"start_at"`
+	EndAt          time.Time   `gorm:"not null" json:"end_at"`
+	Status         string      `gorm:"not null;index" json:"status"`
+	Slices         []AlgoSlice `gorm:"foreignKey:AlgoOrderID" json:"slices,omitempty"`
+	CreatedAt      time.Time   `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time   `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// RemainingQuantity is the part of the parent order that has not been
+// filled yet.
+func (o *AlgoOrder) RemainingQuantity() float64 {
+	return o.Quantity - o.FilledQuantity
+}
+
+// AlgoSlice is one scheduled child order of an algo order. Quantity is the
+// planned size; a slice also sends what earlier slices left unfilled.
+type AlgoSlice struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	AlgoOrderID    uint      `gorm:"not null;index" json:"algo_order_id"`
+	DueAt          time.Time `gorm:"not null;index" json:"due_at"`
+	Quantity       float64   `gorm:"not null" json:"quantity"`
+	SentQuantity   float64   `gorm:"not null;default:0" json:"sent_quantity"`
+	FilledQuantity float64   `gorm:"not null;default:0" json:"filled_quantity"`
+	TradeID        *uint     `json:"trade_id"`
+	Status         string    `gorm:"not null" json:"status"`
+	Error          string    `json:"error,omitempty"`
+}
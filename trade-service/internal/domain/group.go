@@ -0,0 +1,77 @@
+package domain
+
+import "time"
+
+// Order groups link orders whose fills affect each other. In an OCO group a
+// fill on either leg cancels the other. A bracket places a take profit and a
+// stop loss, themselves one-cancels-other, for the filled quantity of its
+// entry order once the entry is done.
+const (
+	GroupTypeBracket = "bracket"
+	GroupTypeOCO     = "oco"
+)
+
+const (
+	GroupStatusActive    = "active"
+	GroupStatusCompleted = "completed" // An exit or leg filled and no order is open
+	GroupStatusCancelled = "cancelled" // Ended with nothing filled, or cancelled by the user
+)
+
+// Roles of the orders of a group.
+const (
+	GroupRoleEntry      = "entry"
+	GroupRoleTakeProfit = "take_profit"
+	GroupRoleStopLoss   = "stop_loss"
+	GroupRoleLeg        = "leg" // Either order of an OCO pair
+)
+
+// OrderGroup is a set of linked orders. The bracket exit prices are kept on
+// the group until the entry is done and the exits are placed.
+type OrderGroup struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	UserID          string    `gorm:"not null;index" json:"user_id"`
+	Type            string    `gorm:"not null" json:"type"`
+	Status          string    `gorm:"not null" json:"status"`
+	TakeProfitPrice float64   `gorm:"not null;default:0" json:"take_profit_price,omitempty"`
+	StopLossPrice   float64   `gorm:"not null;default:0" json:"stop_loss_price,omitempty"`
+	StopLossLimit   float64   `gorm:"not null;default:0" json:"stop_loss_limit,omitempty"` // Releases the stop loss as a limit order if set
+	Orders          []Trade   `gorm:"foreignKey:GroupID" json:"orders"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// BracketExits returns the take profit and stop loss orders that close the
+// filled quantity of a bracket's entry. Both wait for their trigger.
+func (g *OrderGroup) BracketExits(entry *Trade) []Trade {
+	side := SideSell
+	if entry.Side == SideSell {
+		side = SideBuy
+	}
+	exit := Trade{
+		UserID:         entry.UserID,
+		Symbol:         entry.Symbol,
+		Side:           side,
+		TimeInForce:    TimeInForceGTC,
+		Quantity:       entry.FilledQuantity,
+		ReferencePrice: entry.ReferencePrice,
+		Status:         TradeStatusAccepted,
+		GroupID:        &g.ID,
+	}
+
+	takeProfit := exit
+	takeProfit.Type = OrderTypeTakeProfit
+	takeProfit.StopPrice = g.TakeProfitPrice
+	takeProfit.Price = g.TakeProfitPrice
+	takeProfit.GroupRole = GroupRoleTakeProfit
+
+	stopLoss := exit
+	stopLoss.Type = OrderTypeStop
+	stopLoss.StopPrice = g.StopLossPrice
+	stopLoss.GroupRole = GroupRoleStopLoss
+	if g.StopLossLimit > 0 {
+		stopLoss.Type = OrderTypeStopLimit
+		stopLoss.Price = g.StopLossLimit
+	}
+
+	return []Trade{takeProfit, stopLoss}
+}
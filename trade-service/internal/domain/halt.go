@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// Scopes of a trading halt.
+const (
+	HaltScopeGlobal = "global" // Every order
+	HaltScopeSymbol = "symbol" // Orders in one symbol
+	HaltScopeUser   = "user"   // One user's orders
+)
+
+// Halt stops new orders, amendments and releases of waiting orders while it
+// is active. Cancels are always allowed. Automatic halts are placed by the
+// volatility monitor and end by themselves.
+type Halt struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Scope     string     `gorm:"not null" json:"scope"`
+	Target    string     `gorm:"not null;default:''" json:"target,omitempty"` // Symbol or user; empty for a global halt
+	Reason    string     `json:"reason"`
+	Automatic bool       `gorm:"not null;default:false" json:"automatic"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // When the halt ends by itself, nil to last until lifted
+	LiftedAt  *time.Time `gorm:"index" json:"lifted_at,omitempty"`
+	LiftedBy  string     `json:"lifted_by,omitempty"`
+}
+
+// Covers reports whether the halt applies to the user's orders in the
+// symbol.
+func (h *Halt) Covers(userID, symbol string) bool {
+	switch h.Scope {
+	case HaltScopeGlobal:
+		return true
+	case HaltScopeSymbol:
+		return h.Target == symbol
+	case HaltScopeUser:
+		return h.Target == userID
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Journal entry kinds.
+const (
+	EntryDeposit    = "deposit"
+	EntryWithdrawal = "withdrawal"
+	EntrySettlement = "settlement"
+	EntryFee        = "fee"
+)
+
+// Ledger accounts besides the users' own.
+const (
+	AccountExternal = "external" // Money entering or leaving the platform
+	AccountFees     = "fees"     // Fees the platform earned
+)
+
+// DefaultCurrency is the cash currency of symbols without a known quote
+// currency suffix.
+const DefaultCurrency = "USD"
+
+// ledgerEpsilon absorbs float rounding when checking that postings balance.
+const ledgerEpsilon = 1e-6
+
+// quoteCurrencies are the currency suffixes recognized in symbols, longest
+// first so that "BTCUSDT" settles in USDT rather than USD.
+var quoteCurrencies = []string{"USDT", "USDC", "USD", "EUR", "GBP", "JPY"}
+
+// UserAccount is the ledger account holding a user's cash.
+func UserAccount(userID string) string {
+	return "user:" + userID
+}
+
+// QuoteCurrency returns the cash currency trades in the symbol settle in.
+func QuoteCurrency(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	for _, currency := range quoteCurrencies {
+		if len(symbol) > len(currency) && strings.HasSuffix(symbol, currency) {
+			return currency
+		}
+	}
+	return DefaultCurrency
+}
+
+// JournalEntry is one movement of cash, written as postings that sum to
+// zero in every currency.
+type JournalEntry struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Kind        string    `gorm:"not null" json:"kind"`
+	Reference   string    `gorm:"index" json:"reference"` // What caused the entry, e.g. "execution:12"
+	Description string    `json:"description"`
+	Postings    []Posting `gorm:"foreignKey:EntryID" json:"postings"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Posting credits (positive) or debits (negative) an account.
+type Posting struct {
+	ID       uint    `gorm:"primaryKey" json:"-"`
+	EntryID  uint    `gorm:"not null;index" json:"-"`
+	Account  string  `gorm:"not null;index" json:"account"`
+	Currency string  `gorm:"not null" json:"currency"`
+	Amount   float64 `gorm:"not null" json:"amount"`
+}
+
+// NewTransfer builds an entry moving amount of currency from one account to
+// another.
+func NewTransfer(kind, reference, description, currency, from, to string, amount float64) JournalEntry {
+	return JournalEntry{
+		Kind:        kind,
+		Reference:   reference,
+		Description: description,
+		Postings: []Posting{
+			{Account: from, Currency: currency, Amount: -amount},
+			{Account: to, Currency: currency, Amount: amount},
+		},
+	}
+}
+
+// Validate checks that the entry has postings and that they balance in
+// every currency.
+func (e *JournalEntry) Validate() error {
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("journal entry needs at least two postings")
+	}
+	sums := make(map[string]float64)
+	for _, p := range e.Postings {
+		sums[p.Currency] += p.Amount
+	}
+	for currency, sum := range sums {
+		if math.Abs(sum) > ledgerEpsilon {
+			return fmt.Errorf("journal entry unbalanced by %.6f %s", sum, currency)
+		}
+	}
+	return nil
+}
+
+// Balance is a user's cash in one currency. Reserved is held by open buy
+// orders; Available is what is left to trade or withdraw.
+type Balance struct {
+	Currency  string  `json:"currency"`
+	Cash      float64 `json:"cash"`
+	Reserved  float64 `json:"reserved"`
+	Available float64 `json:"available"`
+}
+
+// LedgerCheck is the result of checking the whole ledger. The ledger is
+// consistent when every currency sums to zero and every entry balances.
+type LedgerCheck struct {
+	Consistent        bool               `json:"consistent"`
+	Totals            map[string]float64 `json:"totals"`
+	UnbalancedEntries []uint             `json:"unbalanced_entries"`
+	Entries           int64              `json:"entries"`
+	CheckedAt         time.Time          `json:"checked_at"`
+}
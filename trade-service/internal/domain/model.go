@@ -2,9 +2,275 @@ package domain
 
 import "time"
 
+const (
+	TradeStatusNew             = "new"      // Received, not yet validated against the market
+	TradeStatusAccepted        = "accepted" // Open with nothing filled yet
+	TradeStatusPartiallyFilled = "partially_filled"
+	TradeStatusFilled          = "filled"
+	TradeStatusCancelled       = "cancelled"
+	TradeStatusRejected        = "rejected"
+	TradeStatusExpired         = "expired" // Unfilled remainder of a market, IOC or FOK order, or a DAY order past the close
+)
+
+// tradeTransitions lists the statuses each status may move to. Open orders
+// may "move" to their own status when they are amended or partially filled
+// again. Filled, cancelled, rejected and expired are final.
+var tradeTransitions = map[string][]string{
+	TradeStatusNew: {TradeStatusAccepted, TradeStatusRejected},
+	TradeStatusAccepted: {TradeStatusAccepted, TradeStatusPartiallyFilled, TradeStatusFilled,
+		TradeStatusCancelled, TradeStatusExpired},
+	TradeStatusPartiallyFilled: {TradeStatusPartiallyFilled, TradeStatusFilled,
+		TradeStatusCancelled, TradeStatusExpired},
+}
+
+// CanTransition reports whether an order may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, next := range tradeTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether an order in the given status can still fill.
+func IsOpen(status string) bool {
+	return status == TradeStatusAccepted || status == TradeStatusPartiallyFilled
+}
+
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// Stop, stop limit, take profit and trailing stop orders are conditional:
+// they wait, off the book, until the price reaches StopPrice, and are then
+// released as a market order, or as a limit order at Price if one is set.
+const (
+	OrderTypeMarket       = "market"
+	OrderTypeLimit        = "limit"
+	OrderTypeStop         = "stop"          // Triggers against the order: buys at or above StopPrice, sells at or below
+	OrderTypeStopLimit    = "stop_limit"    // A stop released as a limit order
+	OrderTypeTakeProfit   = "take_profit"   // Triggers in favour of the order: buys at or below StopPrice, sells at or above
+	OrderTypeTrailingStop = "trailing_stop" // A stop whose StopPrice follows the best price at a fixed distance
+)
+
+// IsConditional reports whether orders of the type wait for a trigger.
+func IsConditional(orderType string) bool {
+	switch orderType {
+	case OrderTypeStop, OrderTypeStopLimit, OrderTypeTakeProfit, OrderTypeTrailingStop:
+		return true
+	}
+	return false
+}
+
+const (
+	TimeInForceGTC = "GTC" // Good till cancelled
+	TimeInForceIOC = "IOC" // Immediate or cancel
+	TimeInForceFOK = "FOK" // Fill or kill
+	TimeInForceDAY = "DAY" // Good for the trading day
+)
+
+// Trade is an order placed by a user. Price is the limit price and is zero
+// for market orders; StopPrice is only set for stop orders.
 type Trade struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	UserID         string    `gorm:"not null;index" json:"user_id"`
+	Symbol         string    `gorm:"not null;index" json:"symbol"`
+	Side           string    `gorm:"not null" json:"side"`
+	Type           string    `gorm:"not null" json:"type"`
+	TimeInForce    string    `gorm:"not null" json:"time_in_force"`
+	Quantity       float64   `gorm:"not null" json:"quantity"`
+	FilledQuantity float64   `gorm:"not null;default:0" json:"filled_quantity"`
+	Price          float64   `gorm:"not null" json:"price"`
+	StopPrice      float64   `json:"stop_price,omitempty"`
+	TrailAmount    float64   `gorm:"not null;default:0" json:"trail_amount,omitempty"`  // Distance of a trailing stop from the best price
+	TrailPercent   float64   `gorm:"not null;default:0" json:"trail_percent,omitempty"` // Same, in percent of the best price
+	ReferencePrice float64   `gorm:"not null" json:"reference_price"`                   // Lowest 24h price the trade was validated against
+	Fee            float64   `gorm:"not null;default:0" json:"fee"`                     // Fees charged on the fills so far, in the quote currency
+	Status         string    `gorm:"not null" json:"status"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// Set when a conditional order is released: the type it was placed as
+	// (Type becomes market or limit) and when its trigger was reached
+	TriggeredFrom string     `json:"triggered_from,omitempty"`
+	TriggeredAt   *time.Time `json:"triggered_at,omitempty"`
+
+	// Set for orders of an order group
+	GroupID   *uint  `gorm:"index" json:"group_id,omitempty"`
+	GroupRole string `json:"group_role,omitempty"`
+
+	// ActivatesAt is set while an order placed outside its symbol's session
+	// waits for the next open; ExpiresAt is the session close of a DAY order
+	ActivatesAt *time.Time `json:"activates_at,omitempty"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at,omitempty"`
+}
+
+// RemainingQuantity is the part of the order that has not been filled yet.
+func (t *Trade) RemainingQuantity() float64 {
+	return t.Quantity - t.FilledQuantity
+}
+
+// Triggered reports whether the price reached a conditional order's
+// StopPrice.
+func (t *Trade) Triggered(price float64) bool {
+	favourable := t.Type == OrderTypeTakeProfit
+	if (t.Side == SideBuy) != favourable {
+		return price >= t.StopPrice
+	}
+	return price <= t.StopPrice
+}
+
+// Trail moves a trailing stop's StopPrice to its distance from the price if
+// that is closer to the price than the current one, and reports whether it
+// moved. A sell stop only ever rises, a buy stop only ever falls.
+func (t *Trade) Trail(price float64) bool {
+	distance := t.TrailAmount
+	if t.TrailPercent > 0 {
+		distance = price * t.TrailPercent / 100
+	}
+
+	if t.Side == SideSell {
+		if level := price - distance; level > t.StopPrice {
+			t.StopPrice = level
+			return true
+		}
+		return false
+	}
+	if level := price + distance; t.StopPrice == 0 || level < t.StopPrice {
+		t.StopPrice = level
+		return true
+	}
+	return false
+}
+
+// Release turns a triggered conditional order into the order it places: a
+// limit order at Price if one is set, a market order otherwise.
+func (t *Trade) Release(at time.Time) {
+	t.TriggeredFrom = t.Type
+	t.TriggeredAt = &at
+	t.Type = OrderTypeMarket
+	if t.Price > 0 {
+		t.Type = OrderTypeLimit
+	}
+}
+
+// OrderEvent records one status transition of an order. FromStatus is empty
+// for the event that creates the order.
+type OrderEvent struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	TradeID        uint      `gorm:"not null;index" json:"trade_id"`
+	UserID         string    `gorm:"not null" json:"user_id"`
+	FromStatus     string    `json:"from_status"`
+	ToStatus       string    `gorm:"not null" json:"to_status"`
+	Reason         string    `json:"reason"`
+	Quantity       float64   `json:"quantity"`
+	Price          float64   `json:"price"`
+	FilledQuantity float64   `json:"filled_quantity"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// NewOrderEvent records the order moving from the given status to its
+// current one, with a snapshot of its quantities and price.
+func NewOrderEvent(trade *Trade, from, reason string) OrderEvent {
+	return OrderEvent{
+		TradeID:        trade.ID,
+		UserID:         trade.UserID,
+		FromStatus:     from,
+		ToStatus:       trade.Status,
+		Reason:         reason,
+		Quantity:       trade.Quantity,
+		Price:          trade.Price,
+		FilledQuantity: trade.FilledQuantity,
+	}
+}
+
+// Execution is a fill between a buy and a sell order. The price is always
+// the price of the resting (maker) order.
+type Execution struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Symbol      string    `gorm:"not null;index" json:"symbol"`
+	BuyTradeID  uint      `gorm:"not null;index" json:"buy_trade_id"`
+	SellTradeID uint      `gorm:"not null;index" json:"sell_trade_id"`
+	BuyUserID   string    `gorm:"not null;index" json:"buy_user_id"`
+	SellUserID  string    `gorm:"not null;index" json:"sell_user_id"`
+	TakerSide   string    `gorm:"not null" json:"taker_side"`
+	Price       float64   `gorm:"not null" json:"price"`
+	Quantity    float64   `gorm:"not null" json:"quantity"`
+	BuyFee      float64   `gorm:"not null;default:0" json:"buy_fee"`
+	SellFee     float64   `gorm:"not null;default:0" json:"sell_fee"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Trading modes: live orders trade real cash and positions, paper orders go
+// to a separate simulated book and ledger.
+const (
+	TradingModeLive  = "live"
+	TradingModePaper = "paper"
+)
+
+// AccountSettings are the per-user preferences of an account.
+type AccountSettings struct {
+	UserID      string    `gorm:"primaryKey" json:"user_id"`
+	TradingMode string    `gorm:"not null;default:live" json:"trading_mode"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// DataPoint is a price recorded by the Data Service, as it streams it and as
+// it stores it in its data_points table.
+type DataPoint struct {
 	ID        uint      `gorm:"primaryKey"`
-	UserID    string    `gorm:"not null"`
-	Price     float64   `gorm:"not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	Value     float64   `gorm:"not null"`
+	Timestamp time.Time `gorm:"autoCreateTime"`
+}
+
+// PriceStats are the lowest, highest and last prices of the last 24 hours as
+// reported by the Data Service. Highest and Last are zero if unknown.
+type PriceStats struct {
+	Lowest  float64 `json:"lowest"`
+	Highest float64 `json:"highest"`
+	Last    float64 `json:"last"`
+}
+
+// Mark is the price positions and triggers are checked against: the last
+// price, or the lowest one if the last price is unknown.
+func (s PriceStats) Mark() float64 {
+	if s.Last > 0 {
+		return s.Last
+	}
+	return s.Lowest
+}
+
+// TradeAmendment holds the changes requested for an open order. Nil fields
+// are left unchanged.
+type TradeAmendment struct {
+	Price     *float64
+	StopPrice *float64
+	Quantity  *float64
+}
+
+// TradeFilter narrows a trade listing. Zero values leave a field unfiltered.
+type TradeFilter struct {
+	UserID   string
+	From     time.Time
+	To       time.Time
+	MinPrice *float64
+	MaxPrice *float64
+	Cursor   uint // Only trades with an ID below the cursor are returned
+	Limit    int
+}
+
+// IdempotencyKey is a client-chosen key of a trade submission together with
+// the hash of the request and the response it got, so a retried submission
+// is answered with that response instead of placing the trade again. A zero
+// StatusCode means the first request is still being handled.
+type IdempotencyKey struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      string    `gorm:"not null;uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	Key         string    `gorm:"column:idempotency_key;not null;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	RequestHash string    `gorm:"not null" json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Response    []byte    `json:"response"`
+	CreatedAt   time.Time `gorm:"autoCreateTime;index" json:"created_at"`
 }
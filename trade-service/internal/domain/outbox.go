@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// Outbox event types. Order events are "order." followed by the status the
+// order moved to, e.g. "order.filled".
+const (
+	OutboxOrderPrefix      = "order."
+	OutboxExecutionCreated = "execution.created"
+)
+
+// OutboxEvent is a trade state change waiting to be delivered to the
+// webhooks. It is written in the same transaction as the change.
+type OutboxEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Type      string    `gorm:"not null" json:"type"`
+	TradeID   uint      `gorm:"index" json:"trade_id,omitempty"`
+	Payload   string    `gorm:"type:text;not null" json:"-"` // JSON of the event data
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// OrderEventPayload is the data of an order outbox event: the event with
+// the order's symbol, side and type at the time.
+type OrderEventPayload struct {
+	OrderEvent
+	Symbol string `json:"symbol"`
+	Side   string `json:"side"`
+	Type   string `json:"type"`
+}
+
+// Webhook is a URL the outbox events are delivered to, in order and at
+// least once. Deliveries are signed with the secret.
+type Webhook struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	URL           string     `gorm:"not null" json:"url"`
+	Secret        string     `gorm:"not null" json:"-"`
+	Cursor        uint       `gorm:"not null;default:0" json:"cursor"`   // Last outbox event delivered
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"` // Failed attempts at the next event
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Pending       int64      `gorm:"-" json:"pending"` // Events not delivered yet
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
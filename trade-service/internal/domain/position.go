@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// positionEpsilon absorbs float rounding when a position is closed.
+const positionEpsilon = 1e-9
+
+// Position is what a user holds in a symbol. Quantity is positive when long
+// and negative when short; AverageCost is the average price paid for the
+// open quantity and RealizedPnL the profit of everything closed so far.
+type Position struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	UserID      string    `gorm:"not null;uniqueIndex:idx_position_user_symbol" json:"user_id"`
+	Symbol      string    `gorm:"not null;uniqueIndex:idx_position_user_symbol" json:"symbol"`
+	Quantity    float64   `gorm:"not null" json:"quantity"`
+	AverageCost float64   `gorm:"not null" json:"average_cost"`
+	RealizedPnL float64   `gorm:"not null" json:"realized_pnl"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Apply books a fill of quantity at price on the given side. Fills that
+// extend the position move the average cost; fills against it realize the
+// profit or loss of the closed quantity, and a fill that flips the position
+// opens the rest at the fill price.
+func (p *Position) Apply(side string, quantity, price float64) {
+	signed := quantity
+	if side == SideSell {
+		signed = -quantity
+	}
+
+	// Opening or adding to the position
+	if p.Quantity == 0 || (p.Quantity > 0) == (signed > 0) {
+		open := math.Abs(p.Quantity)
+		p.AverageCost = (open*p.AverageCost + quantity*price) / (open + quantity)
+		p.Quantity += signed
+		return
+	}
+
+	// Reducing, closing or flipping the position
+	closed := math.Min(quantity, math.Abs(p.Quantity))
+	direction := 1.0
+	if p.Quantity < 0 {
+		direction = -1
+	}
+	p.RealizedPnL += closed * (price - p.AverageCost) * direction
+	p.Quantity += signed
+
+	switch {
+	case math.Abs(p.Quantity) < positionEpsilon:
+		p.Quantity = 0
+		p.AverageCost = 0
+	case quantity > closed:
+		p.AverageCost = price
+	}
+}
+
+// PositionValuation is a position marked against the latest market price.
+// The mark fields are nil when no price is available.
+type PositionValuation struct {
+	Position
+	MarkPrice     *float64 `json:"mark_price"`
+	MarketValue   *float64 `json:"market_value"`
+	UnrealizedPnL *float64 `json:"unrealized_pnl"`
+}
+
+// NewPositionValuation marks the position at the given price, or leaves it
+// unmarked if marked is false.
+func NewPositionValuation(p Position, mark float64, marked bool) PositionValuation {
+	v := PositionValuation{Position: p}
+	if marked {
+		value := p.Quantity * mark
+		unrealized := p.Quantity * (mark - p.AverageCost)
+		v.MarkPrice, v.MarketValue, v.UnrealizedPnL = &mark, &value, &unrealized
+	}
+	return v
+}
+
+// Portfolio is every position of a user with the P&L totals. UnrealizedPnL
+// is nil when the positions could not be marked.
+type Portfolio struct {
+	Positions     []PositionValuation `json:"positions"`
+	RealizedPnL   float64             `json:"realized_pnl"`
+	UnrealizedPnL *float64            `json:"unrealized_pnl"`
+}
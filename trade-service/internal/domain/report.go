@@ -0,0 +1,88 @@
+package domain
+
+import "time"
+
+// StatementFill is one fill of a user's order.
+type StatementFill struct {
+	ExecutionID uint      `json:"execution_id"`
+	TradeID     uint      `json:"trade_id"`
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`
+	Quantity    float64   `json:"quantity"`
+	Price       float64   `json:"price"`
+	Notional    float64   `json:"notional"`
+	Fee         float64   `json:"fee"`
+	Currency    string    `json:"currency"`
+	Liquidity   string    `json:"liquidity"` // "maker" or "taker"
+	Time        time.Time `json:"time"`
+}
+
+// CashMovement is one posting of a journal entry to a ledger account.
+type CashMovement struct {
+	EntryID     uint      `json:"entry_id"`
+	Kind        string    `json:"kind"`
+	Reference   string    `json:"reference"`
+	Description string    `json:"description"`
+	Account     string    `json:"-"`
+	Currency    string    `json:"currency"`
+	Amount      float64   `json:"amount"`
+	Time        time.Time `json:"time"`
+}
+
+// CashSummary explains the change of cash in one currency over the day.
+// Fees are negative, as they are paid.
+type CashSummary struct {
+	Currency    string  `json:"currency"`
+	Opening     float64 `json:"opening"`
+	Deposits    float64 `json:"deposits"`
+	Withdrawals float64 `json:"withdrawals"`
+	Trading     float64 `json:"trading"` // Settlements of fills
+	Fees        float64 `json:"fees"`
+	Closing     float64 `json:"closing"`
+}
+
+// StatementPosition is a position at the end of the day.
+type StatementPosition struct {
+	Symbol      string  `json:"symbol"`
+	Quantity    float64 `json:"quantity"`
+	AverageCost float64 `json:"average_cost"`
+	RealizedPnL float64 `json:"realized_pnl"` // Since the position was first opened
+}
+
+// DailyStatement is a user's trading and cash for one day.
+type DailyStatement struct {
+	UserID    string              `json:"user_id"`
+	Fills     []StatementFill     `json:"fills"`
+	Positions []StatementPosition `json:"positions"`
+	Cash      []CashSummary       `json:"cash"`
+	Movements []CashMovement      `json:"movements"`
+}
+
+// SymbolVolume is the trading in one symbol over the day.
+type SymbolVolume struct {
+	Symbol     string  `json:"symbol"`
+	Executions int     `json:"executions"`
+	Quantity   float64 `json:"quantity"`
+	Notional   float64 `json:"notional"`
+	Fees       float64 `json:"fees"` // Paid by both sides
+}
+
+// DailySummary is the firm-wide total of a day. Its cash is the sum of all
+// users' cash.
+type DailySummary struct {
+	Users      int            `json:"users"` // With a statement
+	Executions int            `json:"executions"`
+	Volume     []SymbolVolume `json:"volume"`
+	Cash       []CashSummary  `json:"cash"`
+}
+
+// DailyReport holds the statements of a day, from From up to, not
+// including, To.
+type DailyReport struct {
+	Date        string           `json:"date"` // "2006-01-02", in UTC
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	Summary     *DailySummary    `json:"summary,omitempty"` // Only in the report of all users
+	Statements  []DailyStatement `json:"statements"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
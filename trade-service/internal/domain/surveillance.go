@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// RoundTrip is a user buying and selling at the same price within a short
+// time, which moves no risk and may be a wash trade.
+type RoundTrip struct {
+	UserID           string    `json:"user_id"`
+	Symbol           string    `json:"symbol"`
+	Price            float64   `json:"price"`
+	Quantity         float64   `json:"quantity"`          // Bought and sold back
+	OpenExecutionID  uint      `json:"open_execution_id"` // The earlier fill
+	CloseExecutionID uint      `json:"close_execution_id"`
+	OpenedAt         time.Time `json:"opened_at"`
+	ClosedAt         time.Time `json:"closed_at"`
+	SameCounterparty bool      `json:"same_counterparty"` // Both fills were against the same user
+}
+
+// SelfMatchCount is how many of a user's orders were cancelled to prevent
+// them matching the user's own orders.
+type SelfMatchCount struct {
+	UserID    string `json:"user_id"`
+	Cancelled int    `json:"cancelled"`
+}
+
+// SurveillanceReport lists the suspicious trading between From and To.
+type SurveillanceReport struct {
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	RoundTrips  []RoundTrip      `json:"round_trips"`
+	SelfMatches []SelfMatchCount `json:"self_matches"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
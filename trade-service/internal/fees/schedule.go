@@ -0,0 +1,127 @@
+package fees
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Tier holds the maker and taker rates, in percent of the notional, for
+// users whose 30-day traded volume is at least MinVolume.
+type Tier struct {
+	MinVolume    float64 `json:"min_volume"`
+	MakerPercent float64 `json:"maker_percent"`
+	TakerPercent float64 `json:"taker_percent"`
+}
+
+// Schedule describes how fills are charged: a flat fee once per order, plus
+// a percentage of each fill's notional taken from the tier matching the
+// user's 30-day volume. A plain percentage is a single tier with equal
+// maker and taker rates.
+type Schedule struct {
+	Flat  float64 `json:"flat"`
+	Tiers []Tier  `json:"tiers"`
+}
+
+// DefaultSchedule charges nothing.
+var DefaultSchedule = Schedule{}
+
+// Validate checks the schedule and sorts its tiers by volume.
+func (s *Schedule) Validate() error {
+	if s.Flat < 0 {
+		return fmt.Errorf("flat fee must not be negative")
+	}
+	seen := make(map[float64]bool)
+	for _, t := range s.Tiers {
+		if t.MinVolume < 0 || t.MakerPercent < 0 || t.TakerPercent < 0 {
+			return fmt.Errorf("fee tier at volume %.2f: values must not be negative", t.MinVolume)
+		}
+		if seen[t.MinVolume] {
+			return fmt.Errorf("duplicate fee tier at volume %.2f", t.MinVolume)
+		}
+		seen[t.MinVolume] = true
+	}
+	sort.Slice(s.Tiers, func(i, j int) bool { return s.Tiers[i].MinVolume < s.Tiers[j].MinVolume })
+	return nil
+}
+
+// Rate returns the percentage charged on a fill for a user with the given
+// 30-day volume. Volumes below the first tier pay nothing.
+func (s *Schedule) Rate(volume float64, maker bool) float64 {
+	var rate float64
+	for _, t := range s.Tiers {
+		if volume < t.MinVolume {
+			break
+		}
+		rate = t.TakerPercent
+		if maker {
+			rate = t.MakerPercent
+		}
+	}
+	return rate
+}
+
+// Engine holds the fee schedule. It can be reloaded from the schedule file
+// while the service is running.
+type Engine struct {
+	mu       sync.RWMutex
+	path     string
+	schedule Schedule
+}
+
+// NewEngine loads the schedule from the given JSON file, or uses
+// DefaultSchedule if the path is empty.
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewEngineWithSchedule builds an engine from a schedule that is not read
+// from a file.
+func NewEngineWithSchedule(schedule Schedule) (*Engine, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	return &Engine{schedule: schedule}, nil
+}
+
+// Reload re-reads the schedule file. On error the current schedule stays in
+// place.
+func (e *Engine) Reload() error {
+	schedule := DefaultSchedule
+	if e.path != "" {
+		data, err := os.ReadFile(e.path)
+		if err != nil {
+			return fmt.Errorf("failed to read fee schedule: %v", err)
+		}
+		if err := json.Unmarshal(data, &schedule); err != nil {
+			return fmt.Errorf("failed to parse fee schedule: %v", err)
+		}
+	}
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.schedule = schedule
+	e.mu.Unlock()
+	return nil
+}
+
+// Fee returns the fee of a fill of the given notional. firstFill adds the
+// flat fee, which an order pays once.
+func (e *Engine) Fee(notional, volume float64, maker, firstFill bool) float64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	fee := notional * e.schedule.Rate(volume, maker) / 100
+	if firstFill {
+		fee += e.schedule.Flat
+	}
+	return fee
+}
@@ -0,0 +1,380 @@
+package matching
+
+import (
+	"sort"
+	"sync"
+
+	"simpletrading/tradeservice/internal/domain"
+)
+
+// epsilon absorbs floating point noise when comparing quantities.
+const epsilon = 1e-9
+
+// Self-match policies: what happens when an order would match a resting
+// order of the same user.
+const (
+	CancelNewest = "cancel_newest" // Cancel the rest of the incoming order
+	CancelOldest = "cancel_oldest" // Cancel the resting order and keep matching
+	CancelBoth   = "cancel_both"   // Cancel both
+)
+
+// IsSelfMatchPolicy reports whether the policy is one of the above.
+func IsSelfMatchPolicy(policy string) bool {
+	return policy == CancelNewest || policy == CancelOldest || policy == CancelBoth
+}
+
+// Book holds the resting limit orders for one symbol in price-time priority.
+type Book struct {
+	bids []*domain.Trade // Highest price first, then oldest
+	asks []*domain.Trade // Lowest price first, then oldest
+}
+
+// Result describes what happened to an incoming order.
+type Result struct {
+	Makers        []domain.Trade     // Resting orders touched by the match, with their new fill state or cancelled by a self-match
+	MakerStatuses []string           // Status of each maker before the match
+	Executions    []domain.Execution // One per filled maker; a new order's trade ID is left at zero
+	Rested        bool               // Whether the remainder joined the book
+	Cancelled     []uint             // Linked orders persist cancelled because of the fills; apply takes them off the book
+}
+
+// Engine matches incoming orders against per-symbol order books. An order
+// never matches a resting order of the same user; the self-match policy
+// decides which of the two is cancelled instead.
+type Engine struct {
+	mu     sync.Mutex
+	books  map[string]*Book
+	policy string
+}
+
+// NewEngine creates an engine that cancels the incoming order on a
+// self-match.
+func NewEngine() *Engine {
+	return NewEngineWithPolicy(CancelNewest)
+}
+
+// NewEngineWithPolicy creates an engine with the given self-match policy.
+func NewEngineWithPolicy(policy string) *Engine {
+	return &Engine{books: make(map[string]*Book), policy: policy}
+}
+
+// Load rests open orders, e.g. the ones read back from the database on
+// startup. Orders must be passed oldest first.
+func (e *Engine) Load(orders []domain.Trade) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range orders {
+		order := orders[i]
+		e.book(order.Symbol).rest(&order)
+	}
+}
+
+// Submit matches the order against the book and updates its fill state and
+// status. persist is called with the outcome before the book changes; it is
+// expected to store the order (setting its ID) and the result. If persist
+// fails the book is left untouched and the error is returned.
+func (e *Engine) Submit(order *domain.Trade, persist func(*Result) error) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	book := e.book(order.Symbol)
+	result := book.match(order, e.policy)
+
+	if err := persist(result); err != nil {
+		return nil, err
+	}
+
+	book.apply(order, result)
+	return result, nil
+}
+
+// Preview works out the fills the order would get now without changing the
+// book or the order.
+func (e *Engine) Preview(order *domain.Trade) *Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	probe := *order
+	return e.book(order.Symbol).match(&probe, e.policy)
+}
+
+// Cancel takes an order off its book. persist is called first, with the book
+// locked so no match can touch the order meanwhile; if it fails the order
+// stays on the book.
+func (e *Engine) Cancel(symbol string, id uint, persist func() error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := persist(); err != nil {
+		return err
+	}
+
+	e.book(symbol).remove(id)
+	return nil
+}
+
+// Amend replaces an order with an amended version. amend is called with the
+// book locked, so it sees the order's latest fill state; it returns the
+// amended order and whether it keeps its time priority. Orders that keep
+// their priority are updated in place, all others are matched again as if
+// newly submitted. persist works as for Submit, and if it fails the original
+// order is restored.
+func (e *Engine) Amend(symbol string, id uint, amend func() (*domain.Trade, bool, error), persist func(*Result) error) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	order, keepPriority, err := amend()
+	if err != nil {
+		return nil, err
+	}
+
+	book := e.book(symbol)
+	side, i := book.find(id)
+
+	if keepPriority {
+		result := &Result{Rested: i >= 0}
+		if err := persist(result); err != nil {
+			return nil, err
+		}
+		if i >= 0 {
+			amended := *order
+			side[i] = &amended
+		}
+		return result, nil
+	}
+
+	var original *domain.Trade
+	if i >= 0 {
+		original = side[i]
+		book.remove(id)
+	}
+
+	result := book.match(order, e.policy)
+	if err := persist(result); err != nil {
+		if original != nil {
+			book.restore(original, i)
+		}
+		return nil, err
+	}
+
+	book.apply(order, result)
+	return result, nil
+}
+
+func (e *Engine) book(symbol string) *Book {
+	b, ok := e.books[symbol]
+	if !ok {
+		b = &Book{}
+		e.books[symbol] = b
+	}
+	return b
+}
+
+// match works out the fills for the order without changing the book.
+func (b *Book) match(order *domain.Trade, policy string) *Result {
+	result := &Result{}
+	opposite := b.asks
+	if order.Side == domain.SideSell {
+		opposite = b.bids
+	}
+
+	// Fill or kill orders only trade if the whole quantity is available
+	if order.TimeInForce == domain.TimeInForceFOK && available(order, opposite, policy) < order.RemainingQuantity()-epsilon {
+		order.Status = domain.TradeStatusExpired
+		return result
+	}
+
+	for _, resting := range opposite {
+		remaining := order.RemainingQuantity()
+		if remaining <= epsilon || !crosses(order, resting.Price) {
+			break
+		}
+
+		// A self-match cancels instead of trading, see the policies above
+		if resting.UserID == order.UserID {
+			if policy != CancelNewest {
+				maker := *resting
+				maker.Status = domain.TradeStatusCancelled
+				result.Makers = append(result.Makers, maker)
+				result.MakerStatuses = append(result.MakerStatuses, resting.Status)
+			}
+			if policy == CancelOldest {
+				continue
+			}
+			order.Status = domain.TradeStatusCancelled
+			return result
+		}
+
+		qty := min(remaining, resting.RemainingQuantity())
+		order.FilledQuantity += qty
+
+		maker := *resting
+		maker.FilledQuantity += qty
+		maker.Status = fillStatus(&maker)
+		result.Makers = append(result.Makers, maker)
+		result.MakerStatuses = append(result.MakerStatuses, resting.Status)
+		result.Executions = append(result.Executions, execution(order, &maker, qty))
+	}
+
+	switch {
+	case order.RemainingQuantity() <= epsilon:
+		order.Status = domain.TradeStatusFilled
+	case order.Type == domain.OrderTypeLimit &&
+		(order.TimeInForce == domain.TimeInForceGTC || order.TimeInForce == domain.TimeInForceDAY):
+		order.Status = fillStatus(order)
+		result.Rested = true
+	default:
+		// Market, IOC and FOK remainders never rest
+		order.Status = domain.TradeStatusExpired
+	}
+
+	return result
+}
+
+// apply commits a match result computed by match.
+func (b *Book) apply(order *domain.Trade, result *Result) {
+	for _, maker := range result.Makers {
+		if !domain.IsOpen(maker.Status) {
+			b.remove(maker.ID)
+			continue
+		}
+		for _, resting := range b.side(maker.Side) {
+			if resting.ID == maker.ID {
+				resting.FilledQuantity = maker.FilledQuantity
+				resting.Fee = maker.Fee
+				resting.Status = maker.Status
+			}
+		}
+	}
+
+	if result.Rested {
+		rested := *order
+		b.rest(&rested)
+	}
+
+	for _, id := range result.Cancelled {
+		b.remove(id)
+	}
+}
+
+// rest inserts the order behind all orders at the same or a better price.
+func (b *Book) rest(order *domain.Trade) {
+	if order.Side == domain.SideBuy {
+		i := sort.Search(len(b.bids), func(i int) bool { return b.bids[i].Price < order.Price })
+		b.bids = insert(b.bids, i, order)
+		return
+	}
+	i := sort.Search(len(b.asks), func(i int) bool { return b.asks[i].Price > order.Price })
+	b.asks = insert(b.asks, i, order)
+}
+
+// restore puts an order taken off the book back at its old index, so it
+// keeps its time priority.
+func (b *Book) restore(order *domain.Trade, i int) {
+	if order.Side == domain.SideBuy {
+		b.bids = insert(b.bids, i, order)
+		return
+	}
+	b.asks = insert(b.asks, i, order)
+}
+
+func (b *Book) remove(id uint) bool {
+	for i, o := range b.bids {
+		if o.ID == id {
+			b.bids = append(b.bids[:i], b.bids[i+1:]...)
+			return true
+		}
+	}
+	for i, o := range b.asks {
+		if o.ID == id {
+			b.asks = append(b.asks[:i], b.asks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the side slice holding the order and its index, or -1.
+func (b *Book) find(id uint) ([]*domain.Trade, int) {
+	for _, side := range [][]*domain.Trade{b.bids, b.asks} {
+		for i, o := range side {
+			if o.ID == id {
+				return side, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+func (b *Book) side(side string) []*domain.Trade {
+	if side == domain.SideBuy {
+		return b.bids
+	}
+	return b.asks
+}
+
+func insert(orders []*domain.Trade, i int, order *domain.Trade) []*domain.Trade {
+	orders = append(orders, nil)
+	copy(orders[i+1:], orders[i:])
+	orders[i] = order
+	return orders
+}
+
+// crosses reports whether the order is willing to trade at the given price.
+func crosses(order *domain.Trade, price float64) bool {
+	if order.Type == domain.OrderTypeMarket {
+		return true
+	}
+	if order.Side == domain.SideBuy {
+		return price <= order.Price
+	}
+	return price >= order.Price
+}
+
+// available sums the resting quantity the order could trade against before
+// the self-match policy stops it.
+func available(order *domain.Trade, opposite []*domain.Trade, policy string) float64 {
+	var total float64
+	for _, resting := range opposite {
+		if !crosses(order, resting.Price) {
+			break
+		}
+		if resting.UserID == order.UserID {
+			if policy == CancelOldest {
+				continue
+			}
+			break
+		}
+		total += resting.RemainingQuantity()
+	}
+	return total
+}
+
+func fillStatus(order *domain.Trade) string {
+	switch {
+	case order.RemainingQuantity() <= epsilon:
+		return domain.TradeStatusFilled
+	case order.FilledQuantity > 0:
+		return domain.TradeStatusPartiallyFilled
+	default:
+		return domain.TradeStatusAccepted
+	}
+}
+
+func execution(taker, maker *domain.Trade, qty float64) domain.Execution {
+	exec := domain.Execution{
+		Symbol:    taker.Symbol,
+		TakerSide: taker.Side,
+		Price:     maker.Price,
+		Quantity:  qty,
+	}
+	if taker.Side == domain.SideBuy {
+		exec.BuyUserID, exec.SellUserID = taker.UserID, maker.UserID
+		exec.SellTradeID = maker.ID
+	} else {
+		exec.BuyUserID, exec.SellUserID = maker.UserID, taker.UserID
+		exec.BuyTradeID = maker.ID
+	}
+	return exec
+}
@@ -0,0 +1,165 @@
+package matching
+
+import (
+	"errors"
+	"simpletrading/tradeservice/internal/domain"
+	"testing"
+)
+
+// submit assigns the order an ID, as the repository would, and submits it.
+func submit(t *testing.T, e *Engine, order domain.Trade) (*domain.Trade, *Result) {
+	t.Helper()
+	order.Symbol = "BTCUSD"
+	if order.Type == "" {
+		order.Type = domain.OrderTypeLimit
+	}
+	if order.TimeInForce == "" {
+		order.TimeInForce = domain.TimeInForceGTC
+	}
+
+	result, err := e.Submit(&order, func(*Result) error { return nil })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &order, result
+}
+
+func TestPriceTimePriority(t *testing.T) {
+	e := NewEngine()
+	submit(t, e, domain.Trade{ID: 1, UserID: "a", Side: domain.SideSell, Quantity: 1, Price: 101})
+	submit(t, e, domain.Trade{ID: 2, UserID: "b", Side: domain.SideSell, Quantity: 1, Price: 100})
+	submit(t, e, domain.Trade{ID: 3, UserID: "c", Side: domain.SideSell, Quantity: 1, Price: 100})
+
+	order, result := submit(t, e, domain.Trade{ID: 4, UserID: "d", Side: domain.SideBuy, Quantity: 2.5, Price: 101})
+
+	if len(result.Executions) != 3 {
+		t.Fatalf("expected 3 executions, got %d", len(result.Executions))
+	}
+	// Best price first, then oldest at the same price
+	for i, want := range []uint{2, 3, 1} {
+		if got := result.Executions[i].SellTradeID; got != want {
+			t.Errorf("execution %d: expected maker %d, got %d", i, want, got)
+		}
+	}
+	if result.Executions[2].Quantity != 0.5 || result.Makers[2].Status != domain.TradeStatusPartiallyFilled {
+		t.Errorf("expected a partial fill on the last maker, got %+v", result.Makers[2])
+	}
+	if order.Status != domain.TradeStatusFilled {
+		t.Errorf("expected taker to be filled, got %s", order.Status)
+	}
+}
+
+func TestLimitRemainderRests(t *testing.T) {
+	e := NewEngine()
+	submit(t, e, domain.Trade{ID: 1, UserID: "a", Side: domain.SideSell, Quantity: 1, Price: 100})
+
+	order, result := submit(t, e, domain.Trade{ID: 2, UserID: "b", Side: domain.SideBuy, Quantity: 3, Price: 100})
+	if !result.Rested || order.Status != domain.TradeStatusPartiallyFilled {
+		t.Fatalf("expected remainder to rest, got %+v", order)
+	}
+
+	// The rested remainder is now the best bid
+	_, result = submit(t, e, domain.Trade{ID: 3, UserID: "c", Side: domain.SideSell, Type: domain.OrderTypeMarket, Quantity: 5})
+	if len(result.Executions) != 1 || result.Executions[0].Quantity != 2 || result.Executions[0].BuyTradeID != 2 {
+		t.Errorf("unexpected executions: %+v", result.Executions)
+	}
+}
+
+func TestImmediateOrdersDoNotRest(t *testing.T) {
+	e := NewEngine()
+	submit(t, e, domain.Trade{ID: 1, UserID: "a", Side: domain.SideSell, Quantity: 1, Price: 100})
+
+	order, result := submit(t, e, domain.Trade{ID: 2, UserID: "b", Side: domain.SideBuy, Quantity: 2, Price: 100, TimeInForce: domain.TimeInForceFOK})
+	if len(result.Executions) != 0 || order.Status != domain.TradeStatusExpired {
+		t.Fatalf("expected FOK order to be killed, got %+v", order)
+	}
+
+	order, result = submit(t, e, domain.Trade{ID: 3, UserID: "c", Side: domain.SideBuy, Quantity: 2, Price: 100, TimeInForce: domain.TimeInForceIOC})
+	if len(result.Executions) != 1 || result.Rested || order.Status != domain.TradeStatusExpired {
+		t.Fatalf("expected IOC order to fill 1 and cancel the rest, got %+v", order)
+	}
+}
+
+func TestFailedPersistLeavesBookUntouched(t *testing.T) {
+	e := NewEngine()
+	submit(t, e, domain.Trade{ID: 1, UserID: "a", Side: domain.SideSell, Quantity: 1, Price: 100})
+
+	order := domain.Trade{ID: 2, UserID: "b", Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeMarket, TimeInForce: domain.TimeInForceGTC, Quantity: 1}
+	_, err := e.Submit(&order, func(*Result) error { return errTest })
+	if err != errTest {
+		t.Fatalf("expected persist error, got %v", err)
+	}
+
+	_, result := submit(t, e, domain.Trade{ID: 3, UserID: "c", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 1})
+	if len(result.Executions) != 1 {
+		t.Errorf("expected resting order to still be available")
+	}
+}
+
+func TestFailedAmendKeepsPriority(t *testing.T) {
+	e := NewEngine()
+	submit(t, e, domain.Trade{ID: 1, UserID: "a", Side: domain.SideSell, Quantity: 1, Price: 100})
+	submit(t, e, domain.Trade{ID: 2, UserID: "b", Side: domain.SideSell, Quantity: 1, Price: 100})
+
+	amended := domain.Trade{ID: 1, UserID: "a", Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeLimit, TimeInForce: domain.TimeInForceGTC, Quantity: 1, Price: 99}
+	_, err := e.Amend("BTCUSD", 1, func() (*domain.Trade, bool, error) { return &amended, false, nil },
+		func(*Result) error { return errTest })
+	if err != errTest {
+		t.Fatalf("expected persist error, got %v", err)
+	}
+
+	// The original order is still first in line at its price
+	_, result := submit(t, e, domain.Trade{ID: 3, UserID: "c", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 1})
+	if len(result.Executions) != 1 || result.Executions[0].SellTradeID != 1 || result.Executions[0].Price != 100 {
+		t.Errorf("expected the original order to fill first, got %+v", result.Executions)
+	}
+}
+
+func TestSelfMatchPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy    string
+		filled    float64 // By carol's sell at 100
+		status    string  // Of alice's incoming buy
+		cancelled []uint  // Resting orders of alice cancelled
+	}{
+		{CancelNewest, 1, domain.TradeStatusCancelled, nil},
+		{CancelOldest, 2, domain.TradeStatusFilled, []uint{2}},
+		{CancelBoth, 1, domain.TradeStatusCancelled, []uint{2}},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			e := NewEngineWithPolicy(tc.policy)
+			submit(t, e, domain.Trade{ID: 1, UserID: "carol", Side: domain.SideSell, Quantity: 1, Price: 99})
+			submit(t, e, domain.Trade{ID: 2, UserID: "alice", Side: domain.SideSell, Quantity: 1, Price: 100})
+			submit(t, e, domain.Trade{ID: 3, UserID: "carol", Side: domain.SideSell, Quantity: 1, Price: 100})
+
+			order, result := submit(t, e, domain.Trade{ID: 4, UserID: "alice", Side: domain.SideBuy, Quantity: 2, Price: 100})
+			if order.FilledQuantity != tc.filled || order.Status != tc.status || result.Rested {
+				t.Errorf("expected %.0f filled and %s, got %+v", tc.filled, tc.status, order)
+			}
+			for _, exec := range result.Executions {
+				if exec.BuyUserID == exec.SellUserID {
+					t.Errorf("expected no self-trade, got %+v", exec)
+				}
+			}
+			var cancelled []uint
+			for _, maker := range result.Makers {
+				if maker.Status == domain.TradeStatusCancelled {
+					cancelled = append(cancelled, maker.ID)
+				}
+			}
+			if len(cancelled) != len(tc.cancelled) || (len(cancelled) > 0 && cancelled[0] != tc.cancelled[0]) {
+				t.Errorf("expected %v cancelled, got %v", tc.cancelled, cancelled)
+			}
+
+			// A cancelled resting order is off the book
+			_, result = submit(t, e, domain.Trade{ID: 5, UserID: "bob", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 3})
+			for _, exec := range result.Executions {
+				if exec.SellTradeID == 2 && tc.cancelled != nil {
+					t.Errorf("expected order 2 off the book, got %+v", exec)
+				}
+			}
+		})
+	}
+}
+
+var errTest = errors.New("test error")
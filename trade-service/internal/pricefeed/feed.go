@@ -0,0 +1,263 @@
+package pricefeed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"simpletrading/tradeservice/internal/auth"
+	"simpletrading/tradeservice/internal/domain"
+)
+
+const (
+	window     = 24 * time.Hour
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+
+	// The Data Service sends a heartbeat every 15s, so a stream silent for
+	// much longer is taken for dead, e.g. a half-open connection
+	idleTimeout = 3 * 15 * time.Second
+)
+
+// Fallback is asked for prices while the stream cannot answer.
+type Fallback interface {
+	PriceStats(ctx context.Context) (domain.PriceStats, bool, error)
+	PriceActivity(ctx context.Context, days int, bucket time.Duration) (map[int]float64, error)
+}
+
+// Feed keeps the 24h lowest, highest and last price in memory from the Data
+// Service's event stream, so trades need no request of their own. Until the
+// stream has delivered data, or once it has been down for longer than the
+// staleness bound, prices come from the fallback instead.
+type Feed struct {
+	streamURL    string
+	tokens       *auth.TokenSource
+	fallback     Fallback
+	maxStaleness time.Duration
+	idleTimeout  time.Duration
+	http         *http.Client
+
+	mu        sync.RWMutex
+	points    []domain.DataPoint // Oldest first, none older than the window
+	lastID    uint
+	connected bool
+	downSince time.Time
+
+	subscribers map[chan domain.DataPoint]struct{}
+}
+
+func NewFeed(streamURL string, tokens *auth.TokenSource, fallback Fallback, maxStaleness time.Duration) *Feed {
+	return &Feed{
+		streamURL:    streamURL,
+		tokens:       tokens,
+		fallback:     fallback,
+		maxStaleness: maxStaleness,
+		idleTimeout:  idleTimeout,
+		http:         &http.Client{},
+		downSince:    time.Now(),
+		subscribers:  make(map[chan domain.DataPoint]struct{}),
+	}
+}
+
+// PriceStats returns the prices of the rolling window, or asks the fallback
+// if the window cannot be trusted.
+func (f *Feed) PriceStats(ctx context.Context) (domain.PriceStats, bool, error) {
+	if stats, ok := f.stats(); ok {
+		return stats, false, nil
+	}
+	return f.fallback.PriceStats(ctx)
+}
+
+// PriceActivity asks the fallback; the stream only holds the last 24 hours.
+func (f *Feed) PriceActivity(ctx context.Context, days int, bucket time.Duration) (map[int]float64, error) {
+	return f.fallback.PriceActivity(ctx, days, bucket)
+}
+
+// Run keeps the stream connected until ctx is done, reconnecting with
+// backoff and resuming after the last data point seen. A stream that sends
+// nothing, not even a heartbeat, for the idle timeout counts as down.
+func (f *Feed) Run(ctx context.Context) {
+	backoff := minBackoff
+	for {
+		err := f.consume(ctx)
+		f.setConnected(false)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Price stream disconnected: %v; reconnecting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+		if err == nil {
+			backoff = minBackoff
+		}
+	}
+}
+
+// Add puts a data point into the window. Points already seen are ignored.
+func (f *Feed) Add(dp domain.DataPoint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if dp.ID != 0 && dp.ID <= f.lastID {
+		return
+	}
+	if dp.ID != 0 {
+		f.lastID = dp.ID
+	}
+	f.points = append(f.points, dp)
+	f.evict(time.Now())
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- dp:
+		default: // The subscriber fell behind; it gets the next point
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every new data point and a function
+// that stops the subscription. Points are dropped, not queued, for a
+// subscriber that is still busy with the previous one.
+func (f *Feed) Subscribe() (<-chan domain.DataPoint, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan domain.DataPoint, 1)
+	f.subscribers[ch] = struct{}{}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subscribers, ch)
+	}
+}
+
+func (f *Feed) stats() (domain.PriceStats, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.evict(time.Now())
+	if len(f.points) == 0 {
+		return domain.PriceStats{}, false
+	}
+	if !f.connected && time.Since(f.downSince) > f.maxStaleness {
+		return domain.PriceStats{}, false
+	}
+
+	stats := domain.PriceStats{Lowest: f.points[0].Value, Highest: f.points[0].Value}
+	for _, dp := range f.points {
+		stats.Lowest = min(stats.Lowest, dp.Value)
+		stats.Highest = max(stats.Highest, dp.Value)
+	}
+	stats.Last = f.points[len(f.points)-1].Value
+	return stats, true
+}
+
+// evict drops the points that left the window. Callers hold f.mu.
+func (f *Feed) evict(now time.Time) {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(f.points) && f.points[i].Timestamp.Before(cutoff) {
+		i++
+	}
+	f.points = f.points[i:]
+}
+
+func (f *Feed) setConnected(connected bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.connected && !connected {
+		f.downSince = time.Now()
+	}
+	f.connected = connected
+}
+
+// consume reads the event stream until it ends, fails or stays silent for
+// longer than the idle timeout.
+func (f *Feed) consume(ctx context.Context) error {
+	token, err := f.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("auth failed: %v", err)
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := time.AfterFunc(f.idleTimeout, cancel)
+	defer idle.Stop()
+
+	req, err := http.NewRequestWithContext(streamCtx, "GET", f.streamURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "text/event-stream")
+	f.mu.RLock()
+	if f.lastID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(uint64(f.lastID), 10))
+	}
+	f.mu.RUnlock()
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return f.idleError(ctx, streamCtx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		f.tokens.Invalidate(token)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	f.setConnected(true)
+	log.Println("Price stream connected")
+
+	// Server-sent events: "field: value" lines, blank line ends an event
+	var event, data string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		// Any line, heartbeats included, shows the stream is alive
+		idle.Reset(f.idleTimeout)
+
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event == "datapoint" && data != "" {
+				var dp domain.DataPoint
+				if err := json.Unmarshal([]byte(data), &dp); err != nil {
+					log.Println("Skipping malformed data point:", err)
+				} else {
+					f.Add(dp)
+				}
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	return f.idleError(ctx, streamCtx, scanner.Err())
+}
+
+// idleError replaces err with a clearer one if the stream was cancelled for
+// staying silent rather than by the caller.
+func (f *Feed) idleError(ctx, streamCtx context.Context, err error) error {
+	if streamCtx.Err() != nil && ctx.Err() == nil {
+		return fmt.Errorf("no data or heartbeat for %s", f.idleTimeout)
+	}
+	return err
+}
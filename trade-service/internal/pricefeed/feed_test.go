@@ -0,0 +1,110 @@
+package pricefeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"simpletrading/tradeservice/internal/auth"
+	"simpletrading/tradeservice/internal/domain"
+)
+
+type staticPrices domain.PriceStats
+
+func (s staticPrices) PriceStats(ctx context.Context) (domain.PriceStats, bool, error) {
+	return domain.PriceStats(s), false, nil
+}
+
+func (s staticPrices) PriceActivity(ctx context.Context, days int, bucket time.Duration) (map[int]float64, error) {
+	return nil, nil
+}
+
+func TestFeedPriceStats(t *testing.T) {
+	fallback := staticPrices{Lowest: 1, Highest: 1, Last: 1}
+	feed := NewFeed("", nil, fallback, time.Minute)
+
+	// Nothing streamed yet, so the fallback answers
+	stats, _, _ := feed.PriceStats(context.Background())
+	if stats != domain.PriceStats(fallback) {
+		t.Fatalf("expected fallback prices, got %+v", stats)
+	}
+
+	now := time.Now()
+	feed.setConnected(true)
+	feed.Add(domain.DataPoint{ID: 1, Value: 50, Timestamp: now.Add(-25 * time.Hour)}) // Outside the window
+	feed.Add(domain.DataPoint{ID: 2, Value: 120, Timestamp: now.Add(-2 * time.Hour)})
+	feed.Add(domain.DataPoint{ID: 3, Value: 80, Timestamp: now.Add(-time.Hour)})
+	feed.Add(domain.DataPoint{ID: 4, Value: 100, Timestamp: now})
+	feed.Add(domain.DataPoint{ID: 3, Value: 1000, Timestamp: now}) // Replayed, ignored
+
+	stats, stale, err := feed.PriceStats(context.Background())
+	if err != nil || stale {
+		t.Fatalf("unexpected result: stale=%v err=%v", stale, err)
+	}
+	if want := (domain.PriceStats{Lowest: 80, Highest: 120, Last: 100}); stats != want {
+		t.Errorf("expected %+v, got %+v", want, stats)
+	}
+
+	// A stream that has been down too long no longer answers
+	feed.setConnected(false)
+	feed.downSince = now.Add(-2 * time.Minute)
+	stats, _, _ = feed.PriceStats(context.Background())
+	if stats != domain.PriceStats(fallback) {
+		t.Errorf("expected fallback prices once stale, got %+v", stats)
+	}
+}
+
+func TestFeedReconnectsSilentStream(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "test-token"})
+	}))
+	t.Cleanup(authServer.Close)
+
+	// The stream sends one data point, then stops writing without closing
+	var connects int32
+	streamServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&connects, 1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "id: 1\nevent: datapoint\ndata: {\"id\":1,\"value\":100,\"timestamp\":%q}\n\n", time.Now().Format(time.RFC3339Nano))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(streamServer.Close)
+
+	fallback := staticPrices{Lowest: 1, Highest: 1, Last: 1}
+	feed := NewFeed(streamServer.URL, auth.NewTokenSource(authServer.URL, "id", "secret"), fallback, time.Millisecond)
+	feed.idleTimeout = 100 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go feed.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats, _, _ := feed.PriceStats(context.Background())
+		if stats.Last == 100 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected streamed prices, got %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The silent stream is dropped, so the window goes stale and the feed
+	// reconnects
+	for {
+		stats, _, _ := feed.PriceStats(context.Background())
+		if stats == domain.PriceStats(fallback) && atomic.LoadInt32(&connects) >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the silent stream to be dropped, got %+v after %d connects", stats, atomic.LoadInt32(&connects))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
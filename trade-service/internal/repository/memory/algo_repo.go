@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"simpletrading/tradeservice/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InsertAlgoOrder stores an algo order with its slices.
+func (r *TradeRepository) InsertAlgoOrder(order *domain.AlgoOrder) error {
+	return r.db.Create(order).Error
+}
+
+// GetAlgoOrder returns an algo order with its slices in schedule order.
+func (r *TradeRepository) GetAlgoOrder(id uint) (*domain.AlgoOrder, error) {
+	var order domain.AlgoOrder
+	err := r.db.Preload("Slices", func(db *gorm.DB) *gorm.DB { return db.Order("due_at, id") }).
+		First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ListAlgoOrders returns the user's algo orders, newest first, without
+// their slices.
+func (r *TradeRepository) ListAlgoOrders(userID string, limit int) ([]domain.AlgoOrder, error) {
+	var orders []domain.AlgoOrder
+	err := r.db.Where("user_id = ?", userID).Order("id desc").Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
+// ListDueAlgoOrders returns the working algo orders with a pending slice due
+// at the given time, oldest first.
+func (r *TradeRepository) ListDueAlgoOrders(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&domain.AlgoSlice{}).
+		Distinct("algo_order_id").
+		Where("status = ? AND due_at <= ?", domain.SliceStatusPending, now).
+		Where("algo_order_id IN (?)", r.db.Model(&domain.AlgoOrder{}).Select("id").Where("status = ?", domain.AlgoStatusWorking)).
+		Order("algo_order_id").
+		Pluck("algo_order_id", &ids).Error
+	return ids, err
+}
+
+// SaveAlgoOrder stores the algo order and the given slices in one
+// transaction.
+func (r *TradeRepository) SaveAlgoOrder(order *domain.AlgoOrder, slices ...*domain.AlgoSlice) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Slices").Save(order).Error; err != nil {
+			return err
+		}
+		for _, s := range slices {
+			if err := tx.Save(s).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
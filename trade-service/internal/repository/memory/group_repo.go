@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"fmt"
+	"simpletrading/tradeservice/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// InsertOrderGroup stores a new order group without orders.
+func (r *TradeRepository) InsertOrderGroup(group *domain.OrderGroup) error {
+	return r.db.Omit("Orders").Create(group).Error
+}
+
+// GetOrderGroup returns an order group with its orders, oldest first.
+func (r *TradeRepository) GetOrderGroup(id uint) (*domain.OrderGroup, error) {
+	var group domain.OrderGroup
+	err := r.db.Preload("Orders", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&group, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListOrderGroups returns the user's order groups with their orders, newest
+// first.
+func (r *TradeRepository) ListOrderGroups(userID string, limit int) ([]domain.OrderGroup, error) {
+	var groups []domain.OrderGroup
+	err := r.db.Preload("Orders", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("user_id = ?", userID).Order("id desc").Limit(limit).Find(&groups).Error
+	return groups, err
+}
+
+// SaveGroupStatus sets the status of an active order group. It reports false
+// if the group was no longer active.
+func (r *TradeRepository) SaveGroupStatus(id uint, status string) (bool, error) {
+	result := r.db.Model(&domain.OrderGroup{}).
+		Where("id = ? AND status = ?", id, domain.GroupStatusActive).
+		Update("status", status)
+	return result.RowsAffected > 0, result.Error
+}
+
+// linkOrders applies the order group rules to orders whose state the
+// transaction just saved. A fill on an OCO leg or a bracket exit cancels the
+// open siblings; a bracket entry that is done places its exits for the
+// filled quantity. Groups left without open orders are closed. It returns
+// the IDs of the cancelled orders.
+func linkOrders(tx *gorm.DB, orders []*domain.Trade, executions []domain.Execution) ([]uint, error) {
+	filled := make(map[uint]bool)
+	for _, e := range executions {
+		filled[e.BuyTradeID] = true
+		filled[e.SellTradeID] = true
+	}
+	saved := make(map[uint]bool)
+	for _, order := range orders {
+		saved[order.ID] = true
+	}
+
+	var cancelled []uint
+	var groups []uint
+	for _, order := range orders {
+		if order.GroupID == nil {
+			continue
+		}
+		var group domain.OrderGroup
+		if err := tx.First(&group, *order.GroupID).Error; err != nil {
+			return nil, err
+		}
+		if group.Status != domain.GroupStatusActive {
+			continue
+		}
+		groups = append(groups, group.ID)
+
+		switch {
+		case order.GroupRole == domain.GroupRoleEntry:
+			if order.FilledQuantity > 0 && !domain.IsOpen(order.Status) {
+				if err := placeExits(tx, &group, order); err != nil {
+					return nil, err
+				}
+			}
+		case filled[order.ID]:
+			ids, err := cancelSiblings(tx, order, saved)
+			if err != nil {
+				return nil, err
+			}
+			cancelled = append(cancelled, ids...)
+		}
+	}
+
+	for _, id := range groups {
+		if err := settleGroup(tx, id); err != nil {
+			return nil, err
+		}
+	}
+	return cancelled, nil
+}
+
+// placeExits inserts the take profit and stop loss of a bracket whose entry
+// is done.
+func placeExits(tx *gorm.DB, group *domain.OrderGroup, entry *domain.Trade) error {
+	for _, exit := range group.BracketExits(entry) {
+		if err := tx.Create(&exit).Error; err != nil {
+			return err
+		}
+		placed := domain.NewOrderEvent(&exit, "", fmt.Sprintf("placed by bracket entry %d", entry.ID))
+		if err := insertEvents(tx, &exit, []domain.OrderEvent{placed}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelSiblings cancels the open exits or legs of the order's group other
+// than the order itself. Orders the transaction saves anyway are skipped.
+func cancelSiblings(tx *gorm.DB, order *domain.Trade, saved map[uint]bool) ([]uint, error) {
+	var siblings []domain.Trade
+	err := tx.Where("group_id = ? AND id <> ? AND group_role <> ? AND status IN ?",
+		*order.GroupID, order.ID, domain.GroupRoleEntry,
+		[]string{domain.TradeStatusAccepted, domain.TradeStatusPartiallyFilled}).
+		Find(&siblings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for i := range siblings {
+		sibling := &siblings[i]
+		if saved[sibling.ID] {
+			continue
+		}
+		from := sibling.Status
+		sibling.Status = domain.TradeStatusCancelled
+		if err := saveOrderState(tx, sibling); err != nil {
+			return nil, err
+		}
+		event := domain.NewOrderEvent(sibling, from, fmt.Sprintf("cancelled: linked order %d filled", order.ID))
+		if err := insertEvents(tx, sibling, []domain.OrderEvent{event}); err != nil {
+			return nil, err
+		}
+		ids = append(ids, sibling.ID)
+	}
+	return ids, nil
+}
+
+// settleGroup closes an active group with no open orders: completed if an
+// exit or leg filled, cancelled otherwise.
+func settleGroup(tx *gorm.DB, id uint) error {
+	var orders []domain.Trade
+	if err := tx.Where("group_id = ?", id).Find(&orders).Error; err != nil {
+		return err
+	}
+
+	status := domain.GroupStatusCancelled
+	for _, order := range orders {
+		if domain.IsOpen(order.Status) {
+			return nil
+		}
+		if order.GroupRole != domain.GroupRoleEntry && order.FilledQuantity > 0 {
+			status = domain.GroupStatusCompleted
+		}
+	}
+	return tx.Model(&domain.OrderGroup{}).
+		Where("id = ? AND status = ?", id, domain.GroupStatusActive).
+		Update("status", status).Error
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"simpletrading/tradeservice/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type HaltRepository struct {
+	db *gorm.DB
+}
+
+func NewHaltRepository(db *gorm.DB) *HaltRepository {
+	return &HaltRepository{db: db}
+}
+
+// Insert stores a new halt.
+func (r *HaltRepository) Insert(halt *domain.Halt) error {
+	return r.db.Create(halt).Error
+}
+
+// Get returns a halt by ID.
+func (r *HaltRepository) Get(id uint) (*domain.Halt, error) {
+	var halt domain.Halt
+	if err := r.db.First(&halt, id).Error; err != nil {
+		return nil, err
+	}
+	return &halt, nil
+}
+
+// ListActive returns the halts that are neither lifted nor expired at the
+// given time, oldest first.
+func (r *HaltRepository) ListActive(now time.Time) ([]domain.Halt, error) {
+	var halts []domain.Halt
+	err := r.db.
+		Where("lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", now).
+		Order("id").
+		Find(&halts).Error
+	return halts, err
+}
+
+// Lift ends an active halt. It reports false if the halt was already lifted.
+func (r *HaltRepository) Lift(halt *domain.Halt) (bool, error) {
+	result := r.db.Model(&domain.Halt{}).
+		Where("id = ? AND lifted_at IS NULL", halt.ID).
+		Updates(map[string]interface{}{"lifted_at": halt.LiftedAt, "lifted_by": halt.LiftedBy})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"errors"
+	"simpletrading/tradeservice/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve stores a new key. If the user already holds the key, nothing is
+// stored and the existing key is returned. Keys created before expiredBefore
+// are dropped first so they can be used again.
+func (r *IdempotencyRepository) Reserve(key *domain.IdempotencyKey, expiredBefore time.Time) (*domain.IdempotencyKey, error) {
+	if err := r.db.Where("created_at < ?", expiredBefore).Delete(&domain.IdempotencyKey{}).Error; err != nil {
+		return nil, err
+	}
+
+	// The unique index settles concurrent requests with the same key: only
+	// one insert succeeds and the others find its row
+	createErr := r.db.Create(key).Error
+	if createErr == nil {
+		return nil, nil
+	}
+
+	var existing domain.IdempotencyKey
+	err := r.db.Where("user_id = ? AND idempotency_key = ?", key.UserID, key.Key).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, createErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Complete stores the response of the request the key was reserved for.
+func (r *IdempotencyRepository) Complete(key *domain.IdempotencyKey) error {
+	return r.db.Model(key).Select("status_code", "content_type", "response").Updates(key).Error
+}
+
+// Release deletes a key so the request can be sent again.
+func (r *IdempotencyRepository) Release(id uint) error {
+	return r.db.Delete(&domain.IdempotencyKey{}, id).Error
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"fmt"
+	"simpletrading/tradeservice/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// PostEntry stores a balanced journal entry with its postings.
+func (r *TradeRepository) PostEntry(entry *domain.JournalEntry) error {
+	return postEntry(r.db, entry)
+}
+
+// CashBalances returns the cash of a ledger account per currency.
+func (r *TradeRepository) CashBalances(account string) (map[string]float64, error) {
+	var rows []struct {
+		Currency string
+		Total    float64
+	}
+	err := r.db.Model(&domain.Posting{}).
+		Select("currency, SUM(amount) AS total").
+		Where("account = ?", account).
+		Group("currency").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		balances[row.Currency] = row.Total
+	}
+	return balances, nil
+}
+
+// ListOpenBuys returns the user's buy orders that still wait to be filled.
+func (r *TradeRepository) ListOpenBuys(userID string) ([]domain.Trade, error) {
+	var trades []domain.Trade
+	err := r.db.
+		Where("user_id = ? AND side = ? AND status IN ?", userID, domain.SideBuy,
+			[]string{domain.TradeStatusAccepted, domain.TradeStatusPartiallyFilled}).
+		Find(&trades).Error
+	return trades, err
+}
+
+// ListEntries returns the journal entries touching an account, newest first.
+func (r *TradeRepository) ListEntries(account string, limit int) ([]domain.JournalEntry, error) {
+	var entries []domain.JournalEntry
+	err := r.db.
+		Where("id IN (?)", r.db.Model(&domain.Posting{}).Select("entry_id").Where("account = ?", account)).
+		Preload("Postings").
+		Order("id desc").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// CheckLedger sums every posting per currency and finds the entries whose
+// postings do not balance.
+func (r *TradeRepository) CheckLedger() (*domain.LedgerCheck, error) {
+	check := &domain.LedgerCheck{Totals: make(map[string]float64), UnbalancedEntries: []uint{}}
+
+	var totals []struct {
+		Currency string
+		Total    float64
+	}
+	err := r.db.Model(&domain.Posting{}).
+		Select("currency, SUM(amount) AS total").
+		Group("currency").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range totals {
+		check.Totals[t.Currency] = t.Total
+	}
+
+	err = r.db.Raw(`SELECT DISTINCT entry_id FROM (
+		SELECT entry_id FROM postings GROUP BY entry_id, currency HAVING ABS(SUM(amount)) > ?
+	) ORDER BY entry_id`, 1e-6).Scan(&check.UnbalancedEntries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Model(&domain.JournalEntry{}).Count(&check.Entries).Error; err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// settleExecutions moves the cash of each execution from the buyer to the
+// seller and the fees of both sides to the fees account.
+func settleExecutions(tx *gorm.DB, executions []domain.Execution) error {
+	for _, e := range executions {
+		reference := fmt.Sprintf("execution:%d", e.ID)
+		currency := domain.QuoteCurrency(e.Symbol)
+
+		entries := []domain.JournalEntry{domain.NewTransfer(domain.EntrySettlement, reference,
+			fmt.Sprintf("%.4f %s @ %.2f", e.Quantity, e.Symbol, e.Price), currency,
+			domain.UserAccount(e.BuyUserID), domain.UserAccount(e.SellUserID), e.Price*e.Quantity)}
+		if e.BuyFee > 0 {
+			entries = append(entries, domain.NewTransfer(domain.EntryFee, reference, "buy fee", currency,
+				domain.UserAccount(e.BuyUserID), domain.AccountFees, e.BuyFee))
+		}
+		if e.SellFee > 0 {
+			entries = append(entries, domain.NewTransfer(domain.EntryFee, reference, "sell fee", currency,
+				domain.UserAccount(e.SellUserID), domain.AccountFees, e.SellFee))
+		}
+
+		for i := range entries {
+			if err := postEntry(tx, &entries[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func postEntry(db *gorm.DB, entry *domain.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	return db.Create(entry).Error
+}
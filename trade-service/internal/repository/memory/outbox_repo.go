@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"encoding/json"
+	"simpletrading/tradeservice/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// LastEventID returns the ID of the newest outbox event, zero if there is
+// none.
+func (r *OutboxRepository) LastEventID() (uint, error) {
+	var id uint
+	err := r.db.Model(&domain.OutboxEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	return id, err
+}
+
+// ListEventsAfter returns up to limit outbox events after the given ID,
+// oldest first.
+func (r *OutboxRepository) ListEventsAfter(id uint, limit int) ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+	err := r.db.Where("id > ?", id).Order("id asc").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// CountEventsAfter counts the outbox events after the given ID.
+func (r *OutboxRepository) CountEventsAfter(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.OutboxEvent{}).Where("id > ?", id).Count(&count).Error
+	return count, err
+}
+
+// PruneEvents deletes the outbox events up to the given ID that were
+// created before the given time.
+func (r *OutboxRepository) PruneEvents(upTo uint, before time.Time) (int64, error) {
+	result := r.db.Where("id <= ? AND created_at < ?", upTo, before).Delete(&domain.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// InsertWebhook stores a new webhook.
+func (r *OutboxRepository) InsertWebhook(webhook *domain.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// ListWebhooks returns every webhook, oldest first.
+func (r *OutboxRepository) ListWebhooks() ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	err := r.db.Order("id asc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *OutboxRepository) GetWebhook(id uint) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	if err := r.db.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook.
+func (r *OutboxRepository) DeleteWebhook(id uint) error {
+	return r.db.Delete(&domain.Webhook{}, id).Error
+}
+
+// SaveDelivery stores the delivery progress of a webhook.
+func (r *OutboxRepository) SaveDelivery(webhook *domain.Webhook) error {
+	return r.db.Model(&domain.Webhook{}).Where("id = ?", webhook.ID).Updates(map[string]interface{}{
+		"cursor":          webhook.Cursor,
+		"attempts":        webhook.Attempts,
+		"next_attempt_at": webhook.NextAttemptAt,
+		"last_error":      webhook.LastError,
+	}).Error
+}
+
+// writeOutbox adds the order events and the executions to the outbox in the
+// transaction that stores them. trade, if not nil, is the order most events
+// belong to; the others are read in the transaction.
+func writeOutbox(tx *gorm.DB, trade *domain.Trade, events []domain.OrderEvent, executions []domain.Execution) error {
+	var outbox []domain.OutboxEvent
+	orders := make(map[uint]*domain.Trade)
+	if trade != nil {
+		orders[trade.ID] = trade
+	}
+	for _, event := range events {
+		order, ok := orders[event.TradeID]
+		if !ok {
+			order = &domain.Trade{}
+			if err := tx.Select("id", "symbol", "side", "type").First(order, event.TradeID).Error; err != nil {
+				return err
+			}
+			orders[event.TradeID] = order
+		}
+		payload, err := json.Marshal(domain.OrderEventPayload{OrderEvent: event, Symbol: order.Symbol, Side: order.Side, Type: order.Type})
+		if err != nil {
+			return err
+		}
+		outbox = append(outbox, domain.OutboxEvent{Type: domain.OutboxOrderPrefix + event.ToStatus, TradeID: event.TradeID, Payload: string(payload)})
+	}
+	for _, e := range executions {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		outbox = append(outbox, domain.OutboxEvent{Type: domain.OutboxExecutionCreated, Payload: string(payload)})
+	}
+	if len(outbox) == 0 {
+		return nil
+	}
+	return tx.Create(&outbox).Error
+}
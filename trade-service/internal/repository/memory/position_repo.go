@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"simpletrading/tradeservice/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// ListPositions returns the user's positions, by symbol.
+func (r *TradeRepository) ListPositions(userID string) ([]domain.Position, error) {
+	var positions []domain.Position
+	err := r.db.Where("user_id = ?", userID).Order("symbol asc").Find(&positions).Error
+	return positions, err
+}
+
+// GetPosition returns the user's position in a symbol, or a flat one if the
+// user never traded it.
+func (r *TradeRepository) GetPosition(userID, symbol string) (*domain.Position, error) {
+	return findPosition(r.db, userID, symbol)
+}
+
+// RebuildPositions books every stored execution into the positions if no
+// positions are stored yet, for databases from before positions were kept.
+func (r *TradeRepository) RebuildPositions() error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&domain.Position{}).Count(&count).Error; err != nil || count > 0 {
+			return err
+		}
+
+		var executions []domain.Execution
+		if err := tx.Order("id asc").Find(&executions).Error; err != nil {
+			return err
+		}
+		return applyExecutions(tx, executions)
+	})
+}
+
+// applyExecutions books the executions into the positions of both sides.
+func applyExecutions(tx *gorm.DB, executions []domain.Execution) error {
+	for _, e := range executions {
+		for _, fill := range []struct{ userID, side string }{
+			{e.BuyUserID, domain.SideBuy},
+			{e.SellUserID, domain.SideSell},
+		} {
+			position, err := findPosition(tx, fill.userID, e.Symbol)
+			if err != nil {
+				return err
+			}
+			position.Apply(fill.side, e.Quantity, e.Price)
+			if err := tx.Save(position).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func findPosition(db *gorm.DB, userID, symbol string) (*domain.Position, error) {
+	// Find rather than First, as a missing position is the usual case and
+	// not worth logging
+	var positions []domain.Position
+	err := db.Where("user_id = ? AND symbol = ?", userID, symbol).Limit(1).Find(&positions).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		return &domain.Position{UserID: userID, Symbol: symbol}, nil
+	}
+	return &positions[0], nil
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"simpletrading/tradeservice/internal/domain"
+	"time"
+)
+
+// ListUserExecutionsBefore returns the executions created before t, oldest
+// first. With a user ID only the executions the user was on either side of
+// are returned.
+func (r *TradeRepository) ListUserExecutionsBefore(userID string, t time.Time) ([]domain.Execution, error) {
+	query := r.db.Where("created_at < ?", t)
+	if userID != "" {
+		query = query.Where("buy_user_id = ? OR sell_user_id = ?", userID, userID)
+	}
+	var executions []domain.Execution
+	err := query.Order("id asc").Find(&executions).Error
+	return executions, err
+}
+
+// ListMovements returns the postings of the journal entries created from
+// from up to, not including, to, oldest first. With a user ID only the
+// postings to the user's account are returned.
+func (r *TradeRepository) ListMovements(userID string, from, to time.Time) ([]domain.CashMovement, error) {
+	query := r.db.Model(&domain.Posting{}).
+		Select("journal_entries.id AS entry_id, journal_entries.kind, journal_entries.reference, journal_entries.description, "+
+			"postings.account, postings.currency, postings.amount, journal_entries.created_at AS time").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.entry_id").
+		Where("journal_entries.created_at >= ? AND journal_entries.created_at < ?", from, to)
+	if userID != "" {
+		query = query.Where("postings.account = ?", domain.UserAccount(userID))
+	}
+	var movements []domain.CashMovement
+	err := query.Order("journal_entries.id asc, postings.id asc").Scan(&movements).Error
+	return movements, err
+}
+
+// SumPostingsBefore returns the cash of every ledger account per currency
+// from the journal entries created before t. With a user ID only the user's
+// account is summed.
+func (r *TradeRepository) SumPostingsBefore(userID string, t time.Time) (map[string]map[string]float64, error) {
+	var rows []struct {
+		Account  string
+		Currency string
+		Total    float64
+	}
+	query := r.db.Model(&domain.Posting{}).
+		Select("postings.account, postings.currency, SUM(postings.amount) AS total").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.entry_id").
+		Where("journal_entries.created_at < ?", t)
+	if userID != "" {
+		query = query.Where("postings.account = ?", domain.UserAccount(userID))
+	}
+	err := query.Group("postings.account, postings.currency").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]map[string]float64)
+	for _, row := range rows {
+		if balances[row.Account] == nil {
+			balances[row.Account] = make(map[string]float64)
+		}
+		balances[row.Account][row.Currency] = row.Total
+	}
+	return balances, nil
+}
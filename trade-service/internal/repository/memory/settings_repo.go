@@ -0,0 +1,21 @@
+package memory
+
+import "simpletrading/tradeservice/internal/domain"
+
+// GetSettings returns the user's account settings, or the defaults if the
+// user never changed them.
+func (r *TradeRepository) GetSettings(userID string) (*domain.AccountSettings, error) {
+	var settings []domain.AccountSettings
+	if err := r.db.Where("user_id = ?", userID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	if len(settings) == 0 {
+		return &domain.AccountSettings{UserID: userID, TradingMode: domain.TradingModeLive}, nil
+	}
+	return &settings[0], nil
+}
+
+// SaveSettings stores the user's account settings.
+func (r *TradeRepository) SaveSettings(settings *domain.AccountSettings) error {
+	return r.db.Save(settings).Error
+}
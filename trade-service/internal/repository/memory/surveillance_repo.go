@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"simpletrading/tradeservice/internal/domain"
+	"time"
+)
+
+// ListExecutionsBetween returns the executions created from from up to, not
+// including, to, oldest first.
+func (r *TradeRepository) ListExecutionsBetween(from, to time.Time) ([]domain.Execution, error) {
+	var executions []domain.Execution
+	err := r.db.
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Order("id asc").
+		Find(&executions).Error
+	return executions, err
+}
+
+// CountSelfMatches counts the orders cancelled with the given self-match
+// reason from from up to, not including, to, per user, most first.
+func (r *TradeRepository) CountSelfMatches(reason string, from, to time.Time) ([]domain.SelfMatchCount, error) {
+	var counts []domain.SelfMatchCount
+	err := r.db.Model(&domain.OrderEvent{}).
+		Select("user_id, COUNT(*) AS cancelled").
+		Where("reason = ? AND created_at >= ? AND created_at < ?", reason, from, to).
+		Group("user_id").
+		Order("cancelled desc, user_id").
+		Scan(&counts).Error
+	return counts, err
+}
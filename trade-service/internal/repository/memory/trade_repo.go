@@ -2,6 +2,7 @@ package memory
 
 import (
 	"simpletrading/tradeservice/internal/domain"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -14,8 +15,308 @@ func NewTradeRepository(db *gorm.DB) *TradeRepository {
 	return &TradeRepository{db: db}
 }
 
-func (r *TradeRepository) Insert(trade *domain.Trade) error {
-	return r.db.Create(trade).Error
+// Insert stores a new trade together with the events of its first status
+// transitions. Events with a zero trade ID are attached to the new trade.
+func (r *TradeRepository) Insert(trade *domain.Trade, events ...domain.OrderEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(trade).Error; err != nil {
+			return err
+		}
+		return insertEvents(tx, trade, events)
+	})
+}
+
+// Update saves the status, quantities and prices of an existing trade
+// together with the events describing the change, and applies the order
+// group rules in the same transaction.
+func (r *TradeRepository) Update(trade *domain.Trade, events ...domain.OrderEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := saveOrderState(tx, trade); err != nil {
+			return err
+		}
+		if err := insertEvents(tx, trade, events); err != nil {
+			return err
+		}
+		_, err := linkOrders(tx, []*domain.Trade{trade}, nil)
+		return err
+	})
+}
+
+// SaveMatch stores an order together with the executions it produced, the
+// positions and cash they moved, the new fill state of the resting orders it
+// matched and the resulting events, in one transaction. A trade with a zero
+// ID is inserted; executions and events that refer to it with a zero trade
+// ID are filled in once it has its ID. The order group rules are applied in
+// the same transaction; it returns the IDs of the linked orders they
+// cancelled.
+func (r *TradeRepository) SaveMatch(trade *domain.Trade, makers []domain.Trade, executions []domain.Execution, events []domain.OrderEvent) ([]uint, error) {
+	var cancelled []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if trade.ID == 0 {
+			if err := tx.Create(trade).Error; err != nil {
+				return err
+			}
+		} else if err := saveOrderState(tx, trade); err != nil {
+			return err
+		}
+
+		for i := range makers {
+			if err := saveOrderState(tx, &makers[i]); err != nil {
+				return err
+			}
+		}
+
+		for i := range executions {
+			if executions[i].BuyTradeID == 0 {
+				executions[i].BuyTradeID = trade.ID
+			}
+			if executions[i].SellTradeID == 0 {
+				executions[i].SellTradeID = trade.ID
+			}
+		}
+		if len(executions) > 0 {
+			if err := tx.Create(&executions).Error; err != nil {
+				return err
+			}
+			if err := applyExecutions(tx, executions); err != nil {
+				return err
+			}
+			if err := settleExecutions(tx, executions); err != nil {
+				return err
+			}
+		}
+
+		if err := insertEvents(tx, trade, events); err != nil {
+			return err
+		}
+		if err := writeOutbox(tx, nil, nil, executions); err != nil {
+			return err
+		}
+
+		orders := []*domain.Trade{trade}
+		for i := range makers {
+			orders = append(orders, &makers[i])
+		}
+		var err error
+		cancelled, err = linkOrders(tx, orders, executions)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// ListEvents returns the status transitions of a trade, oldest first.
+func (r *TradeRepository) ListEvents(tradeID uint) ([]domain.OrderEvent, error) {
+	var events []domain.OrderEvent
+	err := r.db.Where("trade_id = ?", tradeID).Order("id asc").Find(&events).Error
+	return events, err
+}
+
+// ListOpen returns the limit orders that are still resting, oldest first.
+// Orders queued for their session are not on the book.
+func (r *TradeRepository) ListOpen() ([]domain.Trade, error) {
+	var trades []domain.Trade
+	err := r.db.
+		Where("type = ? AND status IN ? AND activates_at IS NULL", domain.OrderTypeLimit,
+			[]string{domain.TradeStatusAccepted, domain.TradeStatusPartiallyFilled}).
+		Order("id asc").
+		Find(&trades).Error
+	return trades, err
+}
+
+// ListOpenOrders returns every order that can still fill, oldest first,
+// limited to a user or a symbol if they are not empty.
+func (r *TradeRepository) ListOpenOrders(userID, symbol string) ([]domain.Trade, error) {
+	query := r.db.Where("status IN ?", []string{domain.TradeStatusAccepted, domain.TradeStatusPartiallyFilled})
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if symbol != "" {
+		query = query.Where("symbol = ?", symbol)
+	}
+	var trades []domain.Trade
+	err := query.Order("id asc").Find(&trades).Error
+	return trades, err
+}
+
+// ListOpenOrdersByUserPrefix returns every order that can still fill of the
+// users whose ID starts with the prefix, oldest first.
+func (r *TradeRepository) ListOpenOrdersByUserPrefix(prefix string) ([]domain.Trade, error) {
+	var trades []domain.Trade
+	err := r.db.
+		Where("substr(user_id, 1, ?) = ? AND status IN ?", len(prefix), prefix,
+			[]string{domain.TradeStatusAccepted, domain.TradeStatusPartiallyFilled}).
+		Order("id asc").
+		Find(&trades).Error
+	return trades, err
+}
+
+// HasOpenOrders reports whether the user has an order that can still fill,
+// a working algo order or an active order group.
+func (r *TradeRepository) HasOpenOrders(userID string) (bool, error) {
+	var trades, algos, groups int64
+	if err := r.db.Model(&domain.Trade{}).
+		Where("user_id = ? AND status IN ?", userID, []string{domain.TradeStatusAccepted, domain.TradeStatusPartiallyFilled}).
+		Count(&trades).Error; err != nil {
+		return false, err
+	}
+	if err := r.db.Model(&domain.AlgoOrder{}).
+		Where("user_id = ? AND status = ?", userID, domain.AlgoStatusWorking).
+		Count(&algos).Error; err != nil {
+		return false, err
+	}
+	if err := r.db.Model(&domain.OrderGroup{}).
+		Where("user_id = ? AND status = ?", userID, domain.GroupStatusActive).
+		Count(&groups).Error; err != nil {
+		return false, err
+	}
+	return trades+algos+groups > 0, nil
+}
+
+// ListQueued returns the orders waiting for their symbol's session to open,
+// oldest first.
+func (r *TradeRepository) ListQueued() ([]domain.Trade, error) {
+	var trades []domain.Trade
+	err := r.db.
+		Where("activates_at IS NOT NULL AND status IN ?",
+			[]string{domain.TradeStatusAccepted, domain.TradeStatusPartiallyFilled}).
+		Order("id asc").
+		Find(&trades).Error
+	return trades, err
+}
+
+// ListExpiring returns the open orders whose session closed at or before
+// the given time.
+func (r *TradeRepository) ListExpiring(now time.Time) ([]domain.Trade, error) {
+	var trades []domain.Trade
+	err := r.db.
+		Where("expires_at <= ? AND status IN ?", now,
+			[]string{domain.TradeStatusAccepted, domain.TradeStatusPartiallyFilled}).
+		Order("id asc").
+		Find(&trades).Error
+	return trades, err
+}
+
+// ListConditional returns the conditional orders waiting for their
+// trigger, oldest first.
+func (r *TradeRepository) ListConditional() ([]domain.Trade, error) {
+	var trades []domain.Trade
+	err := r.db.
+		Where("type IN ? AND status = ?", []string{domain.OrderTypeStop, domain.OrderTypeStopLimit,
+			domain.OrderTypeTakeProfit, domain.OrderTypeTrailingStop}, domain.TradeStatusAccepted).
+		Order("id asc").
+		Find(&trades).Error
+	return trades, err
+}
+
+// SaveStopPrice moves the stop price of a conditional order that is still
+// waiting. It reports false if the order was released or cancelled.
+func (r *TradeRepository) SaveStopPrice(trade *domain.Trade) (bool, error) {
+	result := r.db.Model(&domain.Trade{}).
+		Where("id = ? AND type = ? AND status = ?", trade.ID, trade.Type, domain.TradeStatusAccepted).
+		Update("stop_price", trade.StopPrice)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListExecutions returns the fills of a trade, oldest first.
+func (r *TradeRepository) ListExecutions(tradeID uint) ([]domain.Execution, error) {
+	var executions []domain.Execution
+	err := r.db.
+		Where("buy_trade_id = ? OR sell_trade_id = ?", tradeID, tradeID).
+		Order("id asc").
+		Find(&executions).Error
+	return executions, err
+}
+
+// TradedNotional sums the value of the user's executions since the given time.
+func (r *TradeRepository) TradedNotional(userID string, since time.Time) (float64, error) {
+	var total float64
+	err := r.db.Model(&domain.Execution{}).
+		Select("COALESCE(SUM(price * quantity), 0)").
+		Where("(buy_user_id = ? OR sell_user_id = ?) AND created_at >= ?", userID, userID, since).
+		Scan(&total).Error
+	return total, err
+}
+
+// NetPosition returns the user's bought minus sold quantity in a symbol.
+func (r *TradeRepository) NetPosition(userID, symbol string) (float64, error) {
+	position, err := r.GetPosition(userID, symbol)
+	if err != nil {
+		return 0, err
+	}
+	return position.Quantity, nil
+}
+
+func (r *TradeRepository) GetByID(id uint) (*domain.Trade, error) {
+	var trade domain.Trade
+	err := r.db.First(&trade, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// List returns the trades matching the filter, newest first.
+func (r *TradeRepository) List(filter domain.TradeFilter) ([]domain.Trade, error) {
+	query := r.db.Model(&domain.Trade{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.Cursor > 0 {
+		query = query.Where("id < ?", filter.Cursor)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var trades []domain.Trade
+	err := query.Order("id desc").Find(&trades).Error
+	return trades, err
+}
+
+func saveOrderState(tx *gorm.DB, trade *domain.Trade) error {
+	return tx.Model(&domain.Trade{}).Where("id = ?", trade.ID).Updates(map[string]interface{}{
+		"quantity":        trade.Quantity,
+		"filled_quantity": trade.FilledQuantity,
+		"price":           trade.Price,
+		"stop_price":      trade.StopPrice,
+		"fee":             trade.Fee,
+		"status":          trade.Status,
+		"type":            trade.Type,
+		"triggered_from":  trade.TriggeredFrom,
+		"triggered_at":    trade.TriggeredAt,
+		"activates_at":    trade.ActivatesAt,
+	}).Error
+}
+
+// insertEvents stores the events and adds them to the outbox.
+func insertEvents(tx *gorm.DB, trade *domain.Trade, events []domain.OrderEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for i := range events {
+		if events[i].TradeID == 0 {
+			events[i].TradeID = trade.ID
+		}
+	}
+	if err := tx.Create(&events).Error; err != nil {
+		return err
+	}
+	return writeOutbox(tx, trade, events, nil)
 }
 
 // func (r *TradeRepository) GetLowestPriceInLast24Hours() (float64, error) {
@@ -0,0 +1,124 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"simpletrading/tradeservice/internal/domain"
+)
+
+// rulesFile is the layout of the rules file.
+type rulesFile struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// Engine runs the configured rules against incoming orders. Its rules can be
+// reloaded from the rules file while the service is running.
+type Engine struct {
+	mu    sync.RWMutex
+	path  string
+	rules []Rule
+}
+
+// NewEngine loads the rules from the given JSON file, or uses DefaultRules if
+// the path is empty.
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewEngineWithRules builds an engine from rules that are not read from a file.
+func NewEngineWithRules(configs []RuleConfig) (*Engine, error) {
+	rules, err := buildRules(configs)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{rules: rules}, nil
+}
+
+// Reload re-reads the rules file. On error the current rules stay in place.
+func (e *Engine) Reload() error {
+	configs := DefaultRules
+	if e.path != "" {
+		data, err := os.ReadFile(e.path)
+		if err != nil {
+			return fmt.Errorf("failed to read risk rules: %v", err)
+		}
+		var file rulesFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse risk rules: %v", err)
+		}
+		configs = file.Rules
+	}
+
+	rules, err := buildRules(configs)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.rules = rules
+	e.mu.Unlock()
+	return nil
+}
+
+// Evaluate returns every rule the order breaks, in rule order.
+func (e *Engine) Evaluate(order *domain.Trade, ctx *Context) []Violation {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var violations []Violation
+	for _, rule := range e.rules {
+		if v := rule.Check(order, ctx); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	return violations
+}
+
+// PriceBounds returns the tightest lowest and highest price the price rules
+// allow for the order in the given market. Zero means no bound.
+func (e *Engine) PriceBounds(order *domain.Trade, market domain.PriceStats) (low, high float64) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, rule := range e.rules {
+		r, ok := rule.(*priceRule)
+		if !ok || !r.applies(order) {
+			continue
+		}
+		reference := referencePrice(market, r.reference)
+		if reference <= 0 {
+			continue
+		}
+		l, h := r.bounds(reference)
+		low = max(low, l)
+		if h > 0 && (high == 0 || h < high) {
+			high = h
+		}
+	}
+	return low, high
+}
+
+func buildRules(configs []RuleConfig) ([]Rule, error) {
+	seen := make(map[string]bool)
+	rules := make([]Rule, 0, len(configs))
+	for _, cfg := range configs {
+		if seen[cfg.ID] {
+			return nil, fmt.Errorf("duplicate rule id %q", cfg.ID)
+		}
+		seen[cfg.ID] = true
+
+		rule, err := NewRule(cfg)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
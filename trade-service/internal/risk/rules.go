@@ -0,0 +1,227 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"simpletrading/tradeservice/internal/domain"
+)
+
+// Rule types accepted in the rules file.
+const (
+	RuleMinPrice         = "min_price"          // Price at least Percent of the reference price
+	RuleMaxPrice         = "max_price"          // Price at most Percent of the reference price
+	RulePriceCollar      = "price_collar"       // Price within ±Percent of the reference price
+	RuleMaxOrderNotional = "max_order_notional" // Order notional at most Limit
+	RuleMaxDailyNotional = "max_daily_notional" // Notional traded today plus this order at most Limit
+	RulePositionLimit    = "position_limit"     // Absolute position after a full fill at most Limit
+)
+
+// Reference prices a price rule can be measured against.
+const (
+	ReferenceLow  = "low"
+	ReferenceHigh = "high"
+	ReferenceLast = "last"
+)
+
+// Context is what the rules know about the market and the user when an order
+// is checked.
+type Context struct {
+	Market        domain.PriceStats
+	DailyNotional float64 // Notional the user has traded today
+	Position      float64 // The user's net filled quantity in the order's symbol
+}
+
+// Violation is a rule an order broke.
+type Violation struct {
+	RuleID  string `json:"rule_id"`
+	Message string `json:"message"`
+}
+
+// Rule is a single pre-trade check.
+type Rule interface {
+	ID() string
+	Check(order *domain.Trade, ctx *Context) *Violation
+}
+
+// RuleConfig is one entry of the rules file.
+type RuleConfig struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Reference string   `json:"reference,omitempty"`
+	Percent   float64  `json:"percent,omitempty"`
+	Limit     float64  `json:"limit,omitempty"`
+	Symbols   []string `json:"symbols,omitempty"` // Empty applies the rule to every symbol
+}
+
+// DefaultRules keeps the original check: an order may not be priced below
+// half the lowest price of the last 24 hours.
+var DefaultRules = []RuleConfig{
+	{ID: "min-price-half-low", Type: RuleMinPrice, Reference: ReferenceLow, Percent: 50},
+}
+
+// NewRule builds the rule described by a rules file entry.
+func NewRule(cfg RuleConfig) (Rule, error) {
+	if cfg.ID == "" {
+		return nil, fmt.Errorf("rule without id")
+	}
+
+	base := baseRule{id: cfg.ID, symbols: make(map[string]bool)}
+	for _, symbol := range cfg.Symbols {
+		base.symbols[strings.ToUpper(symbol)] = true
+	}
+
+	switch cfg.Type {
+	case RuleMinPrice, RuleMaxPrice, RulePriceCollar:
+		if cfg.Reference != ReferenceLow && cfg.Reference != ReferenceHigh && cfg.Reference != ReferenceLast {
+			return nil, fmt.Errorf("rule %s: reference must be low, high or last", cfg.ID)
+		}
+		if cfg.Percent <= 0 {
+			return nil, fmt.Errorf("rule %s: percent must be positive", cfg.ID)
+		}
+		return &priceRule{baseRule: base, kind: cfg.Type, reference: cfg.Reference, percent: cfg.Percent}, nil
+	case RuleMaxOrderNotional, RuleMaxDailyNotional, RulePositionLimit:
+		if cfg.Limit <= 0 {
+			return nil, fmt.Errorf("rule %s: limit must be positive", cfg.ID)
+		}
+		return &limitRule{baseRule: base, kind: cfg.Type, limit: cfg.Limit}, nil
+	default:
+		return nil, fmt.Errorf("rule %s: unknown type %q", cfg.ID, cfg.Type)
+	}
+}
+
+type baseRule struct {
+	id      string
+	symbols map[string]bool
+}
+
+func (r *baseRule) ID() string {
+	return r.id
+}
+
+func (r *baseRule) applies(order *domain.Trade) bool {
+	return len(r.symbols) == 0 || r.symbols[order.Symbol]
+}
+
+func (r *baseRule) violation(format string, args ...interface{}) *Violation {
+	return &Violation{RuleID: r.id, Message: fmt.Sprintf(format, args...)}
+}
+
+// priceRule bounds the order price relative to a reference price.
+type priceRule struct {
+	baseRule
+	kind      string
+	reference string
+	percent   float64
+}
+
+func (r *priceRule) Check(order *domain.Trade, ctx *Context) *Violation {
+	price := OrderPrice(order)
+	reference := referencePrice(ctx.Market, r.reference)
+
+	// Market orders carry no price of their own, and a missing reference
+	// price gives nothing to measure against
+	if !r.applies(order) || price <= 0 || reference <= 0 {
+		return nil
+	}
+
+	low, high := r.bounds(reference)
+	switch r.kind {
+	case RuleMinPrice:
+		if price < low {
+			return r.violation("trade price too low; must be at least %.2f", low)
+		}
+	case RuleMaxPrice:
+		if price > high {
+			return r.violation("trade price too high; must be at most %.2f", high)
+		}
+	case RulePriceCollar:
+		if price < low || price > high {
+			return r.violation("trade price outside collar; must be between %.2f and %.2f", low, high)
+		}
+	}
+	return nil
+}
+
+// bounds returns the lowest and highest price the rule allows for the
+// reference price. Zero means no bound.
+func (r *priceRule) bounds(reference float64) (low, high float64) {
+	switch r.kind {
+	case RuleMinPrice:
+		return reference * r.percent / 100, 0
+	case RuleMaxPrice:
+		return 0, reference * r.percent / 100
+	default:
+		return reference * (1 - r.percent/100), reference * (1 + r.percent/100)
+	}
+}
+
+// limitRule caps the order's size in notional or quantity terms.
+type limitRule struct {
+	baseRule
+	kind  string
+	limit float64
+}
+
+func (r *limitRule) Check(order *domain.Trade, ctx *Context) *Violation {
+	if !r.applies(order) {
+		return nil
+	}
+
+	switch r.kind {
+	case RuleMaxOrderNotional:
+		if notional := Notional(order, ctx.Market); notional > r.limit {
+			return r.violation("order notional %.2f exceeds %.2f", notional, r.limit)
+		}
+	case RuleMaxDailyNotional:
+		if total := ctx.DailyNotional + Notional(order, ctx.Market); total > r.limit {
+			return r.violation("daily notional %.2f would exceed %.2f", total, r.limit)
+		}
+	case RulePositionLimit:
+		delta := order.RemainingQuantity()
+		if order.Side == domain.SideSell {
+			delta = -delta
+		}
+		// Orders that shrink the position are always allowed
+		projected := ctx.Position + delta
+		if math.Abs(projected) > r.limit && math.Abs(projected) > math.Abs(ctx.Position) {
+			return r.violation("position %.4f in %s would exceed %.4f", projected, order.Symbol, r.limit)
+		}
+	}
+	return nil
+}
+
+// OrderPrice returns the price an order is checked at: the limit price, the
+// stop price for conditional orders released at market, or zero for market
+// orders.
+func OrderPrice(order *domain.Trade) float64 {
+	if domain.IsConditional(order.Type) && order.Price == 0 {
+		return order.StopPrice
+	}
+	return order.Price
+}
+
+// Notional values the unfilled part of an order. Market orders are valued at
+// the last price, or the lowest one if the last price is unknown.
+func Notional(order *domain.Trade, market domain.PriceStats) float64 {
+	price := OrderPrice(order)
+	if price <= 0 {
+		price = market.Last
+	}
+	if price <= 0 {
+		price = market.Lowest
+	}
+	return order.RemainingQuantity() * price
+}
+
+func referencePrice(market domain.PriceStats, reference string) float64 {
+	switch reference {
+	case ReferenceLow:
+		return market.Lowest
+	case ReferenceHigh:
+		return market.Highest
+	default:
+		return market.Last
+	}
+}
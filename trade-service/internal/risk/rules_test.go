@@ -0,0 +1,95 @@
+package risk
+
+import (
+	"math"
+	"simpletrading/tradeservice/internal/domain"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	engine, err := NewEngineWithRules([]RuleConfig{
+		{ID: "min-low", Type: RuleMinPrice, Reference: ReferenceLow, Percent: 50},
+		{ID: "max-high", Type: RuleMaxPrice, Reference: ReferenceHigh, Percent: 150},
+		{ID: "collar", Type: RulePriceCollar, Reference: ReferenceLast, Percent: 10, Symbols: []string{"btcusd"}},
+		{ID: "order-notional", Type: RuleMaxOrderNotional, Limit: 1000},
+		{ID: "daily-notional", Type: RuleMaxDailyNotional, Limit: 5000},
+		{ID: "position", Type: RulePositionLimit, Limit: 10},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	market := domain.PriceStats{Lowest: 80, Highest: 120, Last: 100}
+
+	tests := []struct {
+		name  string
+		order domain.Trade
+		ctx   Context
+		want  []string
+	}{
+		{"within limits", order("BTCUSD", domain.SideBuy, 1, 100), Context{}, nil},
+		{"below low", order("ETHUSD", domain.SideBuy, 1, 30), Context{}, []string{"min-low"}},
+		{"above high", order("ETHUSD", domain.SideSell, 1, 190), Context{}, []string{"max-high"}},
+		{"outside collar", order("BTCUSD", domain.SideBuy, 1, 85), Context{}, []string{"collar"}},
+		{"collar other symbol", order("ETHUSD", domain.SideBuy, 1, 85), Context{}, nil},
+		{"order notional", order("ETHUSD", domain.SideBuy, 11, 100), Context{}, []string{"order-notional", "position"}},
+		{"daily notional", order("ETHUSD", domain.SideBuy, 5, 100), Context{DailyNotional: 4600}, []string{"daily-notional"}},
+		{"position grows", order("ETHUSD", domain.SideBuy, 2, 100), Context{Position: 9}, []string{"position"}},
+		{"position shrinks", order("ETHUSD", domain.SideSell, 2, 100), Context{Position: 12}, nil},
+		{"market order", domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 11}, Context{}, []string{"order-notional", "position"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			ctx.Market = market
+			violations := engine.Evaluate(&tt.order, &ctx)
+
+			if len(violations) != len(tt.want) {
+				t.Fatalf("expected %v, got %+v", tt.want, violations)
+			}
+			for i, id := range tt.want {
+				if violations[i].RuleID != id {
+					t.Errorf("violation %d: expected %s, got %s", i, id, violations[i].RuleID)
+				}
+			}
+		})
+	}
+}
+
+func TestPriceBounds(t *testing.T) {
+	engine, err := NewEngineWithRules([]RuleConfig{
+		{ID: "min-low", Type: RuleMinPrice, Reference: ReferenceLow, Percent: 50},
+		{ID: "max-high", Type: RuleMaxPrice, Reference: ReferenceHigh, Percent: 150},
+		{ID: "collar", Type: RulePriceCollar, Reference: ReferenceLast, Percent: 10, Symbols: []string{"btcusd"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	market := domain.PriceStats{Lowest: 80, Highest: 120, Last: 100}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	if low, high := engine.PriceBounds(&domain.Trade{Symbol: "BTCUSD"}, market); !near(low, 90) || !near(high, 110) {
+		t.Errorf("expected the collar to bound BTCUSD to 90-110, got %.2f-%.2f", low, high)
+	}
+	if low, high := engine.PriceBounds(&domain.Trade{Symbol: "ETHUSD"}, market); !near(low, 40) || !near(high, 180) {
+		t.Errorf("expected ETHUSD bounded to 40-180, got %.2f-%.2f", low, high)
+	}
+}
+
+func TestNewRuleRejectsBadConfig(t *testing.T) {
+	configs := []RuleConfig{
+		{Type: RuleMinPrice, Reference: ReferenceLow, Percent: 50},
+		{ID: "no-reference", Type: RuleMinPrice, Percent: 50},
+		{ID: "no-limit", Type: RuleMaxOrderNotional},
+		{ID: "unknown", Type: "max_leverage", Limit: 3},
+	}
+	for _, cfg := range configs {
+		if _, err := NewRule(cfg); err == nil {
+			t.Errorf("expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func order(symbol, side string, quantity, price float64) domain.Trade {
+	return domain.Trade{Symbol: symbol, Side: side, Type: domain.OrderTypeLimit, Quantity: quantity, Price: price}
+}
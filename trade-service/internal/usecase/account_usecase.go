@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/risk"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// BuyingPowerRuleID is reported when a buy exceeds the available cash
+	BuyingPowerRuleID      = "buying-power"
+	defaultJournalPageSize = 50
+)
+
+var (
+	ErrInvalidAmount     = errors.New("invalid amount")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// IsAdmin reports whether the user may manage accounts.
+func (uc *TradeUsecase) IsAdmin(userID string) bool {
+	for _, admin := range uc.cfg.AdminEmails {
+		if strings.EqualFold(admin, userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Deposit credits the user's cash from outside the platform.
+func (uc *TradeUsecase) Deposit(userID, currency string, amount float64, reference string) (*domain.JournalEntry, error) {
+	currency, err := validateMovement(userID, currency, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := domain.NewTransfer(domain.EntryDeposit, reference, "deposit", currency,
+		domain.AccountExternal, domain.UserAccount(userID), amount)
+	if err := uc.repo.PostEntry(&entry); err != nil {
+		return nil, fmt.Errorf("failed to post deposit: %v", err)
+	}
+
+	fmt.Printf("Deposit: %s %.2f %s\n", userID, amount, currency)
+
+	return &entry, nil
+}
+
+// Withdraw debits the user's cash to outside the platform. Cash reserved by
+// open buy orders cannot be withdrawn.
+func (uc *TradeUsecase) Withdraw(userID, currency string, amount float64, reference string) (*domain.JournalEntry, error) {
+	currency, err := validateMovement(userID, currency, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	balance, err := uc.balance(userID, currency, 0)
+	if err != nil {
+		return nil, err
+	}
+	if amount > balance.Available {
+		return nil, fmt.Errorf("%w: %.2f %s available", ErrInsufficientFunds, balance.Available, currency)
+	}
+
+	entry := domain.NewTransfer(domain.EntryWithdrawal, reference, "withdrawal", currency,
+		domain.UserAccount(userID), domain.AccountExternal, amount)
+	if err := uc.repo.PostEntry(&entry); err != nil {
+		return nil, fmt.Errorf("failed to post withdrawal: %v", err)
+	}
+
+	fmt.Printf("Withdrawal: %s %.2f %s\n", userID, amount, currency)
+
+	return &entry, nil
+}
+
+// GetBalances returns the user's cash per currency with the part reserved by
+// open buy orders, by currency.
+func (uc *TradeUsecase) GetBalances(userID string) ([]domain.Balance, error) {
+	cash, err := uc.repo.CashBalances(domain.UserAccount(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load balances: %v", err)
+	}
+	reserved, err := uc.reserved(userID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := make(map[string]bool)
+	for c := range cash {
+		currencies[c] = true
+	}
+	for c := range reserved {
+		currencies[c] = true
+	}
+
+	balances := make([]domain.Balance, 0, len(currencies))
+	for c := range currencies {
+		balances = append(balances, domain.Balance{
+			Currency:  c,
+			Cash:      cash[c],
+			Reserved:  reserved[c],
+			Available: cash[c] - reserved[c],
+		})
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Currency < balances[j].Currency })
+	return balances, nil
+}
+
+// ListJournal returns the newest journal entries touching the user's cash.
+func (uc *TradeUsecase) ListJournal(userID string) ([]domain.JournalEntry, error) {
+	entries, err := uc.repo.ListEntries(domain.UserAccount(userID), defaultJournalPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journal entries: %v", err)
+	}
+	return entries, nil
+}
+
+// CheckLedger proves that the ledger sums to zero in every currency and that
+// every journal entry balances.
+func (uc *TradeUsecase) CheckLedger() (*domain.LedgerCheck, error) {
+	check, err := uc.repo.CheckLedger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ledger: %v", err)
+	}
+
+	check.Consistent = len(check.UnbalancedEntries) == 0
+	for currency, total := range check.Totals {
+		if total > 1e-6 || total < -1e-6 {
+			log.Printf("Ledger does not sum to zero: %.6f %s", total, currency)
+			check.Consistent = false
+		}
+	}
+	if len(check.UnbalancedEntries) > 0 {
+		log.Printf("Ledger has unbalanced entries: %v", check.UnbalancedEntries)
+	}
+	check.CheckedAt = time.Now()
+	return check, nil
+}
+
+// checkBuyingPower reports a violation if the user's available cash cannot
+// pay for the buy order and its taker fee. Market orders are valued like the
+// risk rules value them.
+func (uc *TradeUsecase) checkBuyingPower(trade *domain.Trade, market domain.PriceStats) (*risk.Violation, error) {
+	currency := domain.QuoteCurrency(trade.Symbol)
+	balance, err := uc.balance(trade.UserID, currency, trade.ID)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := uc.repo.TradedNotional(trade.UserID, time.Now().Add(-feeVolumeWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load 30-day volume: %v", err)
+	}
+
+	required := risk.Notional(trade, market)
+	required += uc.fees.Fee(required, volume, false, trade.Fee == 0)
+	if required <= balance.Available+1e-9 {
+		return nil, nil
+	}
+	return &risk.Violation{
+		RuleID:  BuyingPowerRuleID,
+		Message: fmt.Sprintf("insufficient buying power; order needs %.2f %s, %.2f available", required, currency, balance.Available),
+	}, nil
+}
+
+// balance returns the user's cash in one currency, not counting the order
+// with the given ID (the one being amended) as a reservation.
+func (uc *TradeUsecase) balance(userID, currency string, exclude uint) (domain.Balance, error) {
+	cash, err := uc.repo.CashBalances(domain.UserAccount(userID))
+	if err != nil {
+		return domain.Balance{}, fmt.Errorf("failed to load balances: %v", err)
+	}
+	reserved, err := uc.reserved(userID, exclude)
+	if err != nil {
+		return domain.Balance{}, err
+	}
+	return domain.Balance{
+		Currency:  currency,
+		Cash:      cash[currency],
+		Reserved:  reserved[currency],
+		Available: cash[currency] - reserved[currency],
+	}, nil
+}
+
+// reserved sums the cash held by the user's open buy orders per currency.
+func (uc *TradeUsecase) reserved(userID string, exclude uint) (map[string]float64, error) {
+	orders, err := uc.repo.ListOpenBuys(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open orders: %v", err)
+	}
+
+	reserved := make(map[string]float64)
+	for i := range orders {
+		if orders[i].ID == exclude {
+			continue
+		}
+		reserved[domain.QuoteCurrency(orders[i].Symbol)] += orders[i].RemainingQuantity() * risk.OrderPrice(&orders[i])
+	}
+	return reserved, nil
+}
+
+func validateMovement(userID, currency string, amount float64) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+	if userID == "" {
+		return "", fmt.Errorf("%w: user is required", ErrInvalidAmount)
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("%w: amount must be positive", ErrInvalidAmount)
+	}
+	return currency, nil
+}
@@ -0,0 +1,323 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpletrading/tradeservice/internal/domain"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxAlgoSlices    = 500
+	maxAlgoWindow    = 24 * time.Hour
+	algoSliceEvery   = time.Minute // Default spacing of the slices
+	algoPageSize     = 50
+	algoPollInterval = time.Second
+
+	// vwapHistoryDays is the data service price history the VWAP profile is
+	// built from
+	vwapHistoryDays = 7
+	vwapBucket      = 15 * time.Minute
+)
+
+var ErrAlgoOrderNotFound = errors.New("algo order not found")
+
+// AlgoRequest describes a parent order to execute over a time window.
+type AlgoRequest struct {
+	Symbol   string
+	Side     string
+	Algo     string  // domain.AlgoTWAP or domain.AlgoVWAP
+	Quantity float64 // Total quantity of the parent order
+	Price    float64 // Limit price of every slice; zero sends market slices
+	StartAt  time.Time
+	Duration time.Duration
+	Slices   int // Zero picks one slice a minute
+}
+
+// SubmitAlgoOrder schedules a parent order as slices spread over the window.
+// TWAP gives every slice the same quantity; VWAP sizes them by how much the
+// data service price moved at the same time of day over the last week,
+// falling back to TWAP sizes without history. The slices are sent by
+// RunAlgoOrders.
+func (uc *TradeUsecase) SubmitAlgoOrder(ctx context.Context, userID string, req AlgoRequest) (*domain.AlgoOrder, error) {
+	child := algoChild(&domain.AlgoOrder{Symbol: req.Symbol, Side: req.Side, Price: req.Price}, req.Quantity)
+	if err := ValidateTrade(&child); err != nil {
+		return nil, err
+	}
+
+	if req.Price < 0 {
+		return nil, fmt.Errorf("%w: price must not be negative", ErrInvalidTrade)
+	}
+	algo := strings.ToLower(req.Algo)
+	if algo != domain.AlgoTWAP && algo != domain.AlgoVWAP {
+		return nil, fmt.Errorf("%w: algo must be twap or vwap", ErrInvalidTrade)
+	}
+	if req.Duration <= 0 || req.Duration > maxAlgoWindow {
+		return nil, fmt.Errorf("%w: duration must be positive and at most %s", ErrInvalidTrade, maxAlgoWindow)
+	}
+	slices := req.Slices
+	if slices == 0 {
+		slices = min(max(int(req.Duration/algoSliceEvery), 1), maxAlgoSlices)
+	}
+	if slices < 1 || slices > maxAlgoSlices {
+		return nil, fmt.Errorf("%w: slices must be between 1 and %d", ErrInvalidTrade, maxAlgoSlices)
+	}
+
+	now := time.Now()
+	start := req.StartAt
+	if start.Before(now) {
+		start = now
+	}
+
+	order := &domain.AlgoOrder{
+		UserID:   userID,
+		Symbol:   child.Symbol,
+		Side:     child.Side,
+		Algo:     algo,
+		Quantity: req.Quantity,
+		Price:    req.Price,
+		StartAt:  start,
+		EndAt:    start.Add(req.Duration),
+		Status:   domain.AlgoStatusWorking,
+	}
+
+	step := req.Duration / time.Duration(slices)
+	weights := make([]float64, slices)
+	for i := range weights {
+		weights[i] = 1
+	}
+	if algo == domain.AlgoVWAP {
+		activity, err := uc.prices.PriceActivity(ctx, vwapHistoryDays, vwapBucket)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMarketDataUnavailable, err)
+		}
+		if len(activity) > 0 {
+			for i := range weights {
+				weights[i] = activity[timeOfDayBucket(start.Add(time.Duration(i)*step))]
+			}
+		}
+	}
+
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		// History exists but none at these times of day
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = float64(slices)
+	}
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		order.Slices = append(order.Slices, domain.AlgoSlice{
+			DueAt:    start.Add(time.Duration(i) * step),
+			Quantity: req.Quantity * w / total,
+			Status:   domain.SliceStatusPending,
+		})
+	}
+
+	if err := uc.repo.InsertAlgoOrder(order); err != nil {
+		return nil, fmt.Errorf("failed to save algo order: %v", err)
+	}
+
+	fmt.Printf("Algo order accepted: %s %s %.4f %s in %d slices until %s\n", order.Algo, order.Side, order.Quantity, order.Symbol, len(order.Slices), order.EndAt.Format(time.RFC3339))
+
+	return order, nil
+}
+
+// GetAlgoOrder returns an algo order owned by the user with its slices.
+func (uc *TradeUsecase) GetAlgoOrder(userID string, id uint) (*domain.AlgoOrder, error) {
+	order, err := uc.repo.GetAlgoOrder(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAlgoOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get algo order: %v", err)
+	}
+	if order.UserID != userID {
+		return nil, ErrAlgoOrderNotFound
+	}
+	return order, nil
+}
+
+// ListAlgoOrders returns the user's newest algo orders.
+func (uc *TradeUsecase) ListAlgoOrders(userID string) ([]domain.AlgoOrder, error) {
+	orders, err := uc.repo.ListAlgoOrders(userID, algoPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list algo orders: %v", err)
+	}
+	return orders, nil
+}
+
+// CancelAlgoOrder stops a working algo order. Its slices are immediate or
+// cancel, so no child order is left in the book.
+func (uc *TradeUsecase) CancelAlgoOrder(userID string, id uint) (*domain.AlgoOrder, error) {
+	uc.algos.Lock()
+	defer uc.algos.Unlock()
+
+	order, err := uc.GetAlgoOrder(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != domain.AlgoStatusWorking {
+		return nil, fmt.Errorf("%w: cannot cancel a %s algo order", ErrInvalidTransition, order.Status)
+	}
+
+	order.Status = domain.AlgoStatusCancelled
+	if err := uc.repo.SaveAlgoOrder(order, skipPending(order)...); err != nil {
+		return nil, fmt.Errorf("failed to save algo order: %v", err)
+	}
+
+	fmt.Printf("Algo order cancelled: %d\n", order.ID)
+
+	return order, nil
+}
+
+// RunAlgoOrders sends the slices of the working algo orders as they fall due
+// until ctx is done.
+func (uc *TradeUsecase) RunAlgoOrders(ctx context.Context) {
+	ticker := time.NewTicker(algoPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := uc.sendDueSlices(ctx, now); err != nil {
+				log.Println("Failed to send algo order slices:", err)
+			}
+		}
+	}
+}
+
+// sendDueSlices sends the slices due at the given time.
+func (uc *TradeUsecase) sendDueSlices(ctx context.Context, now time.Time) error {
+	ids, err := uc.repo.ListDueAlgoOrders(now)
+	if err != nil {
+		return err
+	}
+
+	uc.algos.Lock()
+	defer uc.algos.Unlock()
+
+	for _, id := range ids {
+		order, err := uc.repo.GetAlgoOrder(id)
+		if err != nil {
+			return err
+		}
+		if order.Status != domain.AlgoStatusWorking {
+			continue // Cancelled since it was listed
+		}
+		if err := uc.sendSlices(ctx, order, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendSlices places the order's due slices as child orders. A slice sends
+// the remaining quantity less what the later slices are planned for, so it
+// catches up on what earlier slices left unfilled. Slices found due after
+// the window ended are skipped rather than sent at once.
+func (uc *TradeUsecase) sendSlices(ctx context.Context, order *domain.AlgoOrder, now time.Time) error {
+	for i := range order.Slices {
+		slice := &order.Slices[i]
+		if slice.Status != domain.SliceStatusPending || slice.DueAt.After(now) {
+			continue
+		}
+		if !now.Before(order.EndAt) {
+			break
+		}
+
+		var planned float64
+		for _, later := range order.Slices[i+1:] {
+			if later.Status == domain.SliceStatusPending {
+				planned += later.Quantity
+			}
+		}
+		quantity := order.RemainingQuantity() - planned
+
+		if quantity <= 1e-9 {
+			slice.Status = domain.SliceStatusSkipped
+		} else if trade, _, err := uc.PlaceTrade(ctx, order.UserID, algoChild(order, quantity)); err != nil {
+			slice.Status = domain.SliceStatusFailed
+			slice.SentQuantity = quantity
+			slice.Error = err.Error()
+		} else {
+			slice.Status = domain.SliceStatusSent
+			slice.SentQuantity = quantity
+			slice.TradeID = &trade.ID
+			slice.FilledQuantity = trade.FilledQuantity
+			order.FilledQuantity += trade.FilledQuantity
+		}
+
+		if err := uc.repo.SaveAlgoOrder(order, slice); err != nil {
+			return fmt.Errorf("failed to save algo order: %v", err)
+		}
+	}
+
+	// Finish the order once it is filled or nothing is left to send
+	pending := false
+	for _, s := range order.Slices {
+		pending = pending || s.Status == domain.SliceStatusPending
+	}
+	switch {
+	case order.RemainingQuantity() <= 1e-9:
+		order.Status = domain.AlgoStatusCompleted
+	case !pending || !now.Before(order.EndAt):
+		order.Status = domain.AlgoStatusExpired
+	default:
+		return nil
+	}
+	if err := uc.repo.SaveAlgoOrder(order, skipPending(order)...); err != nil {
+		return fmt.Errorf("failed to save algo order: %v", err)
+	}
+
+	fmt.Printf("Algo order %s: %d, filled %.4f of %.4f\n", order.Status, order.ID, order.FilledQuantity, order.Quantity)
+
+	return nil
+}
+
+// algoChild builds the immediate-or-cancel child order of an algo order.
+func algoChild(order *domain.AlgoOrder, quantity float64) domain.Trade {
+	child := domain.Trade{
+		Symbol:      order.Symbol,
+		Side:        order.Side,
+		Type:        domain.OrderTypeMarket,
+		TimeInForce: domain.TimeInForceIOC,
+		Quantity:    quantity,
+	}
+	if order.Price > 0 {
+		child.Type = domain.OrderTypeLimit
+		child.Price = order.Price
+	}
+	return child
+}
+
+// skipPending marks the order's pending slices skipped and returns them.
+func skipPending(order *domain.AlgoOrder) []*domain.AlgoSlice {
+	var skipped []*domain.AlgoSlice
+	for i := range order.Slices {
+		if order.Slices[i].Status == domain.SliceStatusPending {
+			order.Slices[i].Status = domain.SliceStatusSkipped
+			skipped = append(skipped, &order.Slices[i])
+		}
+	}
+	return skipped
+}
+
+// timeOfDayBucket is the VWAP profile bucket the time falls in.
+func timeOfDayBucket(t time.Time) int {
+	t = t.UTC()
+	sinceMidnight := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+	return int(sinceMidnight / vwapBucket)
+}
@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestVWAPSlicesFollowPriceActivity(t *testing.T) {
+	// The price moves three times as much in the second hour as in the first
+	start := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1).Add(9 * time.Hour)
+	first := timeOfDayBucket(start)
+	activity := map[int]float64{first: 0.01, first + 4: 0.03}
+	uc := newTestUsecase(t, memory.NewTradeRepository(setupTestDB(t)), setupTestServicesWithActivity(t, 100, activity))
+
+	order, err := uc.SubmitAlgoOrder(context.Background(), "alice@example.com", AlgoRequest{
+		Symbol: "BTCUSD", Side: domain.SideBuy, Algo: "vwap", Quantity: 4, StartAt: start, Duration: 2 * time.Hour, Slices: 8,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order.Slices) != 2 || order.Slices[0].Quantity != 1 || order.Slices[1].Quantity != 3 ||
+		!order.Slices[1].DueAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected slices of 1 and 3 an hour apart, got %+v", order.Slices)
+	}
+}
+
+func TestTWAPSlicesCatchUp(t *testing.T) {
+	uc := newTestUsecase(t, memory.NewTradeRepository(setupTestDB(t)), setupTestServices(t, 100))
+	ctx := context.Background()
+
+	order, err := uc.SubmitAlgoOrder(ctx, "alice@example.com", AlgoRequest{
+		Symbol: "btcusd", Side: domain.SideBuy, Algo: "TWAP", Quantity: 4, Price: 100, Duration: 4 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order.Slices) != 4 || order.Slices[3].Quantity != 1 {
+		t.Fatalf("expected 4 slices of 1, got %+v", order.Slices)
+	}
+
+	// The second slice finds nothing to buy, so the third sends 2
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 1, 100))
+	for i := 0; i < 4; i++ {
+		if i == 2 {
+			uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 5, 100))
+		}
+		if err := uc.sendDueSlices(ctx, order.StartAt.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	order, _ = uc.GetAlgoOrder("alice@example.com", order.ID)
+	if order.Status != domain.AlgoStatusCompleted || order.FilledQuantity != 4 {
+		t.Errorf("expected the order to complete, got %s with %.2f filled", order.Status, order.FilledQuantity)
+	}
+	if s := order.Slices[1]; s.SentQuantity != 1 || s.FilledQuantity != 0 {
+		t.Errorf("unexpected second slice: %+v", s)
+	}
+	if s := order.Slices[2]; s.SentQuantity != 2 || s.FilledQuantity != 2 {
+		t.Errorf("expected the third slice to catch up, got %+v", s)
+	}
+
+	if _, err := uc.CancelAlgoOrder("alice@example.com", order.ID); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected ErrInvalidTransition, got %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpletrading/tradeservice/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+const groupPageSize = 50
+
+var ErrOrderGroupNotFound = errors.New("order group not found")
+
+// BracketRequest is an entry order with the exits that close it once it is
+// done.
+type BracketRequest struct {
+	Entry         domain.Trade
+	TakeProfit    float64 // Limit price the position is taken profit at
+	StopLoss      float64 // Stop price the position is closed at
+	StopLossLimit float64 // Optional limit price of the stop loss
+}
+
+// PlaceBracket places the entry order of a bracket. When the entry is done
+// (filled, or cancelled or expired after a partial fill) the repository
+// places a take profit and a stop loss for the filled quantity, which cancel
+// each other once either fills.
+func (uc *TradeUsecase) PlaceBracket(ctx context.Context, userID string, req BracketRequest) (*domain.OrderGroup, error) {
+	entry := req.Entry
+	if err := ValidateTrade(&entry); err != nil {
+		return nil, err
+	}
+	if entry.Type != domain.OrderTypeMarket && entry.Type != domain.OrderTypeLimit {
+		return nil, fmt.Errorf("%w: a bracket entry must be a market or limit order", ErrInvalidTrade)
+	}
+
+	group := &domain.OrderGroup{
+		UserID:          userID,
+		Type:            domain.GroupTypeBracket,
+		Status:          domain.GroupStatusActive,
+		TakeProfitPrice: req.TakeProfit,
+		StopLossPrice:   req.StopLoss,
+		StopLossLimit:   req.StopLossLimit,
+	}
+	if err := validateBracket(group, &entry); err != nil {
+		return nil, err
+	}
+
+	market, err := uc.marketPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.InsertOrderGroup(group); err != nil {
+		return nil, fmt.Errorf("failed to save order group: %v", err)
+	}
+
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	entry.GroupID = &group.ID
+	entry.GroupRole = domain.GroupRoleEntry
+	if _, _, err := uc.placeTrade(userID, entry, market); err != nil {
+		uc.closeGroup(group.ID)
+		return nil, err
+	}
+
+	fmt.Printf("Bracket accepted: %d %s %s take profit %.2f stop loss %.2f\n", group.ID, entry.Side, entry.Symbol, group.TakeProfitPrice, group.StopLossPrice)
+
+	return uc.GetOrderGroup(userID, group.ID)
+}
+
+// validateBracket checks the exits lie on either side of the entry: above it
+// for the take profit of a buy, below it for the stop loss.
+func validateBracket(group *domain.OrderGroup, entry *domain.Trade) error {
+	if group.TakeProfitPrice <= 0 || group.StopLossPrice <= 0 {
+		return fmt.Errorf("%w: take_profit and stop_loss must be positive", ErrInvalidTrade)
+	}
+	if group.StopLossLimit < 0 {
+		return fmt.Errorf("%w: stop_loss_limit must not be negative", ErrInvalidTrade)
+	}
+
+	low, high := group.StopLossPrice, group.TakeProfitPrice
+	if entry.Side == domain.SideSell {
+		low, high = high, low
+	}
+	if low >= high {
+		return fmt.Errorf("%w: the stop loss must be on the losing side of the take profit", ErrInvalidTrade)
+	}
+	if entry.Type == domain.OrderTypeLimit && (entry.Price <= low || entry.Price >= high) {
+		return fmt.Errorf("%w: the entry price must lie between the stop loss and the take profit", ErrInvalidTrade)
+	}
+
+	// The exits go through the same checks as orders placed directly
+	probe := *entry
+	probe.FilledQuantity = probe.Quantity
+	for _, exit := range group.BracketExits(&probe) {
+		if err := ValidateTrade(&exit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlaceOCO places two orders that cancel each other: once either fills the
+// repository cancels the other. If the first fills as it is placed, the
+// second is not placed at all.
+func (uc *TradeUsecase) PlaceOCO(ctx context.Context, userID string, legs []domain.Trade) (*domain.OrderGroup, error) {
+	if len(legs) != 2 {
+		return nil, fmt.Errorf("%w: an OCO group has exactly two orders", ErrInvalidTrade)
+	}
+	for i := range legs {
+		if err := ValidateTrade(&legs[i]); err != nil {
+			return nil, err
+		}
+		// Both legs must be able to wait for the other
+		if legs[i].Type == domain.OrderTypeMarket ||
+			legs[i].TimeInForce == domain.TimeInForceIOC || legs[i].TimeInForce == domain.TimeInForceFOK {
+			return nil, fmt.Errorf("%w: OCO orders must not be market, IOC or FOK orders", ErrInvalidTrade)
+		}
+	}
+	if legs[0].Symbol != legs[1].Symbol {
+		return nil, fmt.Errorf("%w: both OCO orders must be for the same symbol", ErrInvalidTrade)
+	}
+
+	market, err := uc.marketPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &domain.OrderGroup{UserID: userID, Type: domain.GroupTypeOCO, Status: domain.GroupStatusActive}
+	if err := uc.repo.InsertOrderGroup(group); err != nil {
+		return nil, fmt.Errorf("failed to save order group: %v", err)
+	}
+
+	// Holding funds across both legs keeps any match, and so any fill of the
+	// first leg, from running until the second is placed
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	var placed []*domain.Trade
+	for _, leg := range legs {
+		leg.GroupID = &group.ID
+		leg.GroupRole = domain.GroupRoleLeg
+		trade, _, err := uc.placeTrade(userID, leg, market)
+		if err != nil {
+			uc.closeGroup(group.ID)
+			for _, p := range placed {
+				if _, cerr := uc.CancelOrder(userID, p.ID); cerr != nil {
+					log.Printf("Failed to cancel OCO order %d: %v", p.ID, cerr)
+				}
+			}
+			return nil, err
+		}
+		if trade.FilledQuantity > 0 {
+			break
+		}
+		placed = append(placed, trade)
+	}
+
+	fmt.Printf("OCO accepted: %d %s\n", group.ID, legs[0].Symbol)
+
+	return uc.GetOrderGroup(userID, group.ID)
+}
+
+// GetOrderGroup returns an order group owned by the user with its orders.
+func (uc *TradeUsecase) GetOrderGroup(userID string, id uint) (*domain.OrderGroup, error) {
+	group, err := uc.repo.GetOrderGroup(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrderGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order group: %v", err)
+	}
+	if group.UserID != userID {
+		return nil, ErrOrderGroupNotFound
+	}
+	return group, nil
+}
+
+// ListOrderGroups returns the user's newest order groups.
+func (uc *TradeUsecase) ListOrderGroups(userID string) ([]domain.OrderGroup, error) {
+	groups, err := uc.repo.ListOrderGroups(userID, groupPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order groups: %v", err)
+	}
+	return groups, nil
+}
+
+// CancelOrderGroup cancels an active group and its open orders. The group is
+// closed first, so a bracket entry cancelled here places no exits.
+func (uc *TradeUsecase) CancelOrderGroup(userID string, id uint) (*domain.OrderGroup, error) {
+	group, err := uc.GetOrderGroup(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := uc.repo.SaveGroupStatus(id, domain.GroupStatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel order group: %v", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot cancel a %s order group", ErrInvalidTransition, group.Status)
+	}
+
+	for _, order := range group.Orders {
+		if !domain.IsOpen(order.Status) {
+			continue
+		}
+		// The order may have been filled since the group was read
+		if _, err := uc.CancelOrder(userID, order.ID); err != nil && !errors.Is(err, ErrInvalidTransition) {
+			return nil, err
+		}
+	}
+
+	fmt.Printf("Order group cancelled: %d\n", id)
+
+	return uc.GetOrderGroup(userID, id)
+}
+
+// closeGroup cancels a group whose orders could not all be placed.
+func (uc *TradeUsecase) closeGroup(id uint) {
+	if _, err := uc.repo.SaveGroupStatus(id, domain.GroupStatusCancelled); err != nil {
+		log.Printf("Failed to cancel order group %d: %v", id, err)
+	}
+}
@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"context"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestOrderGroupsCancelLinkedOrders(t *testing.T) {
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, setupTestServices(t, 100))
+	ctx := context.Background()
+
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 2, 100))
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideBuy, 3, 85))
+
+	// The market entry fills at once, so the exits are placed with it
+	bracket, err := uc.PlaceBracket(ctx, "alice@example.com", BracketRequest{
+		Entry:      domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 2},
+		TakeProfit: 120,
+		StopLoss:   90,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bracket.Orders) != 3 || bracket.Orders[1].GroupRole != domain.GroupRoleTakeProfit ||
+		bracket.Orders[2].GroupRole != domain.GroupRoleStopLoss || bracket.Orders[2].Quantity != 2 {
+		t.Fatalf("expected the entry and both exits, got %+v", bracket.Orders)
+	}
+
+	oco, err := uc.PlaceOCO(ctx, "alice@example.com", []domain.Trade{
+		limitOrder(domain.SideSell, 1, 130),
+		{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeStop, Quantity: 1, StopPrice: 80},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The stop loss sells into bob's bid, then the OCO stop takes the rest
+	for _, price := range []float64{89, 79} {
+		waiting, _ := repo.ListConditional()
+		if err := uc.triggerOrders(waiting, price, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	bracket, _ = uc.GetOrderGroup("alice@example.com", bracket.ID)
+	if bracket.Status != domain.GroupStatusCompleted || bracket.Orders[1].Status != domain.TradeStatusCancelled ||
+		bracket.Orders[2].Status != domain.TradeStatusFilled {
+		t.Errorf("expected the stop loss to fill and cancel the take profit, got %+v", bracket)
+	}
+	oco, _ = uc.GetOrderGroup("alice@example.com", oco.ID)
+	if oco.Status != domain.GroupStatusCompleted || oco.Orders[0].Status != domain.TradeStatusCancelled {
+		t.Errorf("expected the stop fill to cancel the limit leg, got %+v", oco)
+	}
+
+	// The cancelled leg is off the book too
+	buy, _, err := uc.PlaceTrade(ctx, "carol@example.com", limitOrder(domain.SideBuy, 1, 130))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buy.FilledQuantity != 0 {
+		t.Errorf("expected nothing left to buy from, got %+v", buy)
+	}
+}
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	volatilityPollInterval = time.Second
+	// haltedBySystem is the creator of automatic halts
+	haltedBySystem = "system"
+)
+
+var (
+	ErrInvalidHalt  = errors.New("invalid halt")
+	ErrHaltNotFound = errors.New("halt not found")
+	// ErrTradingHalted means an active halt covers the order.
+	ErrTradingHalted = errors.New("trading halted")
+)
+
+// HaltRequest describes a halt an admin places.
+type HaltRequest struct {
+	Scope    string // domain.HaltScopeGlobal, domain.HaltScopeSymbol or domain.HaltScopeUser
+	Target   string // The symbol or user; empty for a global halt
+	Reason   string
+	Duration time.Duration // Zero lasts until lifted
+}
+
+// HaltUsecase keeps the trading halts. Halts are stored with the live
+// trades and cover paper trading too.
+type HaltUsecase struct {
+	repo *memory.HaltRepository
+	cfg  *config.Config
+
+	// samples are the recent prices the volatility monitor measures moves
+	// over, oldest first
+	mu      sync.Mutex
+	samples []priceSample
+}
+
+type priceSample struct {
+	at    time.Time
+	price float64
+}
+
+func NewHaltUsecase(repo *memory.HaltRepository, cfg *config.Config) *HaltUsecase {
+	return &HaltUsecase{repo: repo, cfg: cfg}
+}
+
+// Halt stops trading in the requested scope.
+func (uc *HaltUsecase) Halt(createdBy string, req HaltRequest) (*domain.Halt, error) {
+	halt := &domain.Halt{Scope: strings.ToLower(req.Scope), Target: strings.TrimSpace(req.Target), Reason: req.Reason, CreatedBy: createdBy}
+	switch halt.Scope {
+	case domain.HaltScopeGlobal:
+		if halt.Target != "" {
+			return nil, fmt.Errorf("%w: a global halt has no target", ErrInvalidHalt)
+		}
+	case domain.HaltScopeSymbol:
+		halt.Target = strings.ToUpper(halt.Target)
+	case domain.HaltScopeUser:
+	default:
+		return nil, fmt.Errorf("%w: scope must be global, symbol or user", ErrInvalidHalt)
+	}
+	if halt.Scope != domain.HaltScopeGlobal && halt.Target == "" {
+		return nil, fmt.Errorf("%w: a %s halt needs a target", ErrInvalidHalt, halt.Scope)
+	}
+	if req.Duration < 0 {
+		return nil, fmt.Errorf("%w: duration must not be negative", ErrInvalidHalt)
+	}
+	if req.Duration > 0 {
+		expiresAt := time.Now().Add(req.Duration)
+		halt.ExpiresAt = &expiresAt
+	}
+
+	if err := uc.repo.Insert(halt); err != nil {
+		return nil, fmt.Errorf("failed to save halt: %v", err)
+	}
+
+	fmt.Printf("Trading halted: %d %s %s by %s\n", halt.ID, halt.Scope, halt.Target, createdBy)
+
+	return halt, nil
+}
+
+// Lift ends a halt.
+func (uc *HaltUsecase) Lift(liftedBy string, id uint) (*domain.Halt, error) {
+	halt, err := uc.repo.Get(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrHaltNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get halt: %v", err)
+	}
+
+	now := time.Now()
+	halt.LiftedAt = &now
+	halt.LiftedBy = liftedBy
+	ok, err := uc.repo.Lift(halt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lift halt: %v", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: halt %d is already lifted", ErrInvalidTransition, id)
+	}
+
+	fmt.Printf("Trading halt lifted: %d by %s\n", halt.ID, liftedBy)
+
+	return halt, nil
+}
+
+// ListActive returns the halts in force.
+func (uc *HaltUsecase) ListActive() ([]domain.Halt, error) {
+	halts, err := uc.repo.ListActive(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list halts: %v", err)
+	}
+	return halts, nil
+}
+
+// Check returns an error wrapping ErrTradingHalted if a halt covers the
+// user's orders in the symbol.
+func (uc *HaltUsecase) Check(userID, symbol string) error {
+	halts, err := uc.ListActive()
+	if err != nil {
+		return err
+	}
+	return haltError(halts, userID, symbol)
+}
+
+// haltError reports the first of the halts covering the user's orders in
+// the symbol.
+func haltError(halts []domain.Halt, userID, symbol string) error {
+	for _, halt := range halts {
+		if !halt.Covers(userID, symbol) {
+			continue
+		}
+		if halt.Reason != "" {
+			return fmt.Errorf("%w: %s halt %d: %s", ErrTradingHalted, halt.Scope, halt.ID, halt.Reason)
+		}
+		return fmt.Errorf("%w: %s halt %d", ErrTradingHalted, halt.Scope, halt.ID)
+	}
+	return nil
+}
+
+// RunVolatilityHalts halts all trading for VolatilityHaltDuration whenever
+// the price moves more than VolatilityHaltPercent within
+// VolatilityHaltWindow, until ctx is done.
+func (uc *HaltUsecase) RunVolatilityHalts(ctx context.Context, prices PriceSource) {
+	ticker := time.NewTicker(volatilityPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			// Stale prices say nothing about the current move
+			stats, stale, err := prices.PriceStats(ctx)
+			if err != nil || stale || stats.Last <= 0 {
+				continue
+			}
+			if _, err := uc.observePrice(now, stats.Last); err != nil {
+				log.Println("Failed to check volatility:", err)
+			}
+		}
+	}
+}
+
+// observePrice records a price and places an automatic halt if the moves
+// within the window exceed the limit. The window starts over after a halt,
+// so the same move does not halt trading twice.
+func (uc *HaltUsecase) observePrice(now time.Time, price float64) (*domain.Halt, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	cutoff := now.Add(-uc.cfg.VolatilityHaltWindow)
+	kept := uc.samples[:0]
+	for _, s := range uc.samples {
+		if s.at.After(cutoff) {
+			kept = append(kept, s)
+		}
+	}
+	uc.samples = append(kept, priceSample{at: now, price: price})
+
+	low, high := price, price
+	for _, s := range uc.samples {
+		low, high = min(low, s.price), max(high, s.price)
+	}
+	move := (high - low) / low * 100
+	if move <= uc.cfg.VolatilityHaltPercent {
+		return nil, nil
+	}
+
+	expiresAt := now.Add(uc.cfg.VolatilityHaltDuration)
+	halt := &domain.Halt{
+		Scope:     domain.HaltScopeGlobal,
+		Reason:    fmt.Sprintf("price moved %.2f%% within %s", move, uc.cfg.VolatilityHaltWindow),
+		Automatic: true,
+		CreatedBy: haltedBySystem,
+		ExpiresAt: &expiresAt,
+	}
+	if err := uc.repo.Insert(halt); err != nil {
+		return nil, fmt.Errorf("failed to save halt: %v", err)
+	}
+	uc.samples = nil
+
+	fmt.Printf("Trading halted: %d volatility, %s until %s\n", halt.ID, halt.Reason, expiresAt.Format(time.RFC3339))
+
+	return halt, nil
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestHaltsStopTrading(t *testing.T) {
+	cfg := setupTestServices(t, 100)
+	cfg.VolatilityHaltPercent = 10
+	cfg.VolatilityHaltWindow = 5 * time.Minute
+	cfg.VolatilityHaltDuration = time.Minute
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, cfg)
+	ctx := context.Background()
+
+	resting, _, _ := uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 2, 100))
+	uc.PlaceTrade(ctx, "carol@example.com", limitOrder(domain.SideSell, 1, 101))
+
+	halt, err := uc.halts.Halt("admin@example.com", HaltRequest{Scope: "user", Target: "bob@example.com", Reason: "review"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 1, 100)); !errors.Is(err, ErrTradingHalted) {
+		t.Errorf("expected ErrTradingHalted, got %v", err)
+	}
+	if cancelled, err := uc.CancelHaltedOrders(halt); err != nil || cancelled != 1 {
+		t.Fatalf("expected bob's order cancelled, got %d, %v", cancelled, err)
+	}
+	if trade, _ := uc.GetTrade("bob@example.com", resting.ID); trade.Status != domain.TradeStatusCancelled {
+		t.Errorf("expected the resting order cancelled, got %q", trade.Status)
+	}
+
+	// Other users still trade, but against carol's order only
+	_, executions, err := uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 1, 101))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executions) != 1 || executions[0].Price != 101 {
+		t.Errorf("expected a fill from carol at 101, got %+v", executions)
+	}
+
+	if _, err := uc.halts.Lift("admin@example.com", halt.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := uc.halts.Lift("admin@example.com", halt.ID); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected lifting twice to fail, got %v", err)
+	}
+	if _, _, err := uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 1, 100)); err != nil {
+		t.Errorf("expected bob to trade after the lift, got %v", err)
+	}
+
+	// A 12% move within the window halts everyone until it expires
+	start := time.Now()
+	uc.halts.observePrice(start, 100)
+	if auto, _ := uc.halts.observePrice(start.Add(time.Minute), 105); auto != nil {
+		t.Fatalf("expected no halt on a 5%% move, got %+v", auto)
+	}
+	auto, err := uc.halts.observePrice(start.Add(2*time.Minute), 112)
+	if err != nil || auto == nil || !auto.Automatic || auto.Scope != domain.HaltScopeGlobal {
+		t.Fatalf("expected an automatic global halt, got %+v, %v", auto, err)
+	}
+	if _, _, err := uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 1, 90)); !errors.Is(err, ErrTradingHalted) {
+		t.Errorf("expected ErrTradingHalted, got %v", err)
+	}
+	if again, _ := uc.halts.observePrice(start.Add(3*time.Minute), 113); again != nil {
+		t.Errorf("expected the move to halt trading once, got %+v", again)
+	}
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"time"
+)
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	// ErrIdempotencyKeyReused means the key was first sent with a different
+	// request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+	// ErrRequestInProgress means the first request with the key has not been
+	// answered yet.
+	ErrRequestInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+type IdempotencyUsecase struct {
+	repo *memory.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyUsecase creates a usecase that remembers keys for ttl.
+func NewIdempotencyUsecase(repo *memory.IdempotencyRepository, ttl time.Duration) *IdempotencyUsecase {
+	return &IdempotencyUsecase{repo: repo, ttl: ttl}
+}
+
+// Begin reserves the user's key for the request with the given hash. If the
+// key was already answered, the stored key with its response (a non-zero
+// StatusCode) is returned and the request must not run again. Otherwise the
+// caller now holds the key and must Finish it.
+func (uc *IdempotencyUsecase) Begin(userID, key, requestHash string) (*domain.IdempotencyKey, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+
+	record := &domain.IdempotencyKey{UserID: userID, Key: key, RequestHash: requestHash}
+	existing, err := uc.repo.Reserve(record, time.Now().Add(-uc.ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %v", err)
+	}
+	if existing == nil {
+		return record, nil
+	}
+
+	switch {
+	case existing.RequestHash != requestHash:
+		return nil, ErrIdempotencyKeyReused
+	case existing.StatusCode == 0:
+		return nil, ErrRequestInProgress
+	}
+	return existing, nil
+}
+
+// Finish stores the response to the request a key was reserved for. Server
+// errors are not stored: nothing was placed, so the key is released and the
+// client may retry it.
+func (uc *IdempotencyUsecase) Finish(record *domain.IdempotencyKey, statusCode int, contentType string, response []byte) error {
+	if statusCode >= 500 {
+		if err := uc.repo.Release(record.ID); err != nil {
+			return fmt.Errorf("failed to release idempotency key: %v", err)
+		}
+		return nil
+	}
+
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Response = response
+	if err := uc.repo.Complete(record); err != nil {
+		return fmt.Errorf("failed to save idempotent response: %v", err)
+	}
+	return nil
+}
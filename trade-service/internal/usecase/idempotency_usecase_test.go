@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"errors"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	uc := NewIdempotencyUsecase(memory.NewIdempotencyRepository(setupTestDB(t)), time.Hour)
+
+	record, err := uc.Begin("user@test.com", "key-1", "hash-a")
+	if err != nil || record.StatusCode != 0 {
+		t.Fatalf("expected a fresh key, got %+v, %v", record, err)
+	}
+
+	// Retried before the first request was answered
+	if _, err := uc.Begin("user@test.com", "key-1", "hash-a"); !errors.Is(err, ErrRequestInProgress) {
+		t.Errorf("expected ErrRequestInProgress, got %v", err)
+	}
+
+	if err := uc.Finish(record, 200, "application/json", []byte(`{"status":"trade accepted"}`)); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+
+	replay, err := uc.Begin("user@test.com", "key-1", "hash-a")
+	if err != nil || replay.StatusCode != 200 || string(replay.Response) != `{"status":"trade accepted"}` {
+		t.Errorf("expected the stored response, got %+v, %v", replay, err)
+	}
+
+	if _, err := uc.Begin("user@test.com", "key-1", "hash-b"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+
+	// Keys belong to a user
+	if other, err := uc.Begin("other@test.com", "key-1", "hash-b"); err != nil || other.StatusCode != 0 {
+		t.Errorf("expected a fresh key for another user, got %+v, %v", other, err)
+	}
+
+	// A failed request releases its key so it can be retried
+	record, _ = uc.Begin("user@test.com", "key-2", "hash-a")
+	if err := uc.Finish(record, 503, "text/plain", []byte("market data unavailable")); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if retry, err := uc.Begin("user@test.com", "key-2", "hash-a"); err != nil || retry.StatusCode != 0 {
+		t.Errorf("expected the key to be released, got %+v, %v", retry, err)
+	}
+}
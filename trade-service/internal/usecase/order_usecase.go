@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/matching"
+)
+
+// CancelOrder cancels an open order owned by the user and takes it off the
+// order book.
+func (uc *TradeUsecase) CancelOrder(userID string, id uint) (*domain.Trade, error) {
+	trade, err := uc.GetTrade(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return uc.cancelOrder(trade, "cancelled by user")
+}
+
+// CancelHaltedOrders cancels the open orders the halt covers and returns
+// how many it cancelled. Orders that fill or are cancelled meanwhile are
+// skipped.
+func (uc *TradeUsecase) CancelHaltedOrders(halt *domain.Halt) (int, error) {
+	var userID, symbol string
+	switch halt.Scope {
+	case domain.HaltScopeUser:
+		userID = halt.Target
+	case domain.HaltScopeSymbol:
+		symbol = halt.Target
+	}
+	orders, err := uc.repo.ListOpenOrders(userID, symbol)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list open orders: %v", err)
+	}
+	return uc.cancelOrders(orders, fmt.Sprintf("cancelled by halt %d", halt.ID))
+}
+
+// CancelOpenOrdersByUserPrefix cancels the open orders of the users whose
+// ID starts with the prefix and returns how many it cancelled.
+func (uc *TradeUsecase) CancelOpenOrdersByUserPrefix(prefix, reason string) (int, error) {
+	orders, err := uc.repo.ListOpenOrdersByUserPrefix(prefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list open orders: %v", err)
+	}
+	return uc.cancelOrders(orders, reason)
+}
+
+// cancelOrders cancels the given orders and returns how many it cancelled.
+// Orders that fill or are cancelled meanwhile are skipped.
+func (uc *TradeUsecase) cancelOrders(orders []domain.Trade, reason string) (int, error) {
+	cancelled := 0
+	for i := range orders {
+		_, err := uc.cancelOrder(&orders[i], reason)
+		if errors.Is(err, ErrInvalidTransition) {
+			continue
+		}
+		if err != nil {
+			return cancelled, err
+		}
+		cancelled++
+	}
+	return cancelled, nil
+}
+
+// cancelOrder cancels an open order and takes it off the order book,
+// recording the reason in its history.
+func (uc *TradeUsecase) cancelOrder(trade *domain.Trade, reason string) (*domain.Trade, error) {
+	id := trade.ID
+	err := uc.engine.Cancel(trade.Symbol, id, func() error {
+		// Re-read with the book locked so a fill that raced the request is seen
+		current, err := uc.repo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		trade = current
+
+		from := trade.Status
+		if !domain.CanTransition(from, domain.TradeStatusCancelled) {
+			return fmt.Errorf("%w: cannot cancel a %s order", ErrInvalidTransition, from)
+		}
+
+		trade.Status = domain.TradeStatusCancelled
+		return uc.repo.Update(trade, domain.NewOrderEvent(trade, from, reason))
+	})
+	if err != nil {
+		return nil, orderError("cancel", err)
+	}
+
+	fmt.Printf("Trade cancelled: %d\n", trade.ID)
+
+	return trade, nil
+}
+
+// AmendOrder changes the price, stop price or quantity of an open order owned
+// by the user. Lowering the quantity keeps the order's place in the book;
+// any other change sends it through matching again, so it may fill at once.
+func (uc *TradeUsecase) AmendOrder(ctx context.Context, userID string, id uint, amendment domain.TradeAmendment) (*domain.Trade, []domain.Execution, error) {
+	if amendment.Price == nil && amendment.StopPrice == nil && amendment.Quantity == nil {
+		return nil, nil, fmt.Errorf("%w: nothing to amend", ErrInvalidTrade)
+	}
+
+	trade, err := uc.GetTrade(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	market, err := uc.marketPrices(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	var from string
+	var amended domain.OrderEvent
+	match, err := uc.engine.Amend(trade.Symbol, id, func() (*domain.Trade, bool, error) {
+		// Re-read with the book locked so a fill that raced the request is seen
+		current, err := uc.repo.GetByID(id)
+		if err != nil {
+			return nil, false, err
+		}
+		trade = current
+		from = trade.Status
+
+		previous := trade.Quantity
+		keepPriority, err := applyAmendment(trade, amendment)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// A pure quantity decrease only lowers risk, so it skips the rules
+		// and is allowed while trading is halted
+		if amendment.Price != nil || amendment.StopPrice != nil || trade.Quantity > previous {
+			if err := uc.halts.Check(trade.UserID, trade.Symbol); err != nil {
+				return nil, false, err
+			}
+			if err := uc.checkRisk(trade, market); err != nil {
+				return nil, false, err
+			}
+		}
+
+		amended = domain.NewOrderEvent(trade, from, "amended")
+		return trade, keepPriority, nil
+	}, func(result *matching.Result) error {
+		if err := uc.chargeFees(trade, result); err != nil {
+			return err
+		}
+		events := append([]domain.OrderEvent{amended}, matchEvents(trade, from, result)...)
+		return uc.saveMatch(trade, result, events)
+	})
+	if err != nil {
+		return nil, nil, orderError("amend", err)
+	}
+
+	fmt.Printf("Trade amended: %d %.4f @ %.2f\n", trade.ID, trade.Quantity, trade.Price)
+
+	return trade, match.Executions, nil
+}
+
+// ListOrderEvents returns the status history of an order owned by the user.
+func (uc *TradeUsecase) ListOrderEvents(userID string, id uint) ([]domain.OrderEvent, error) {
+	if _, err := uc.GetTrade(userID, id); err != nil {
+		return nil, err
+	}
+
+	events, err := uc.repo.ListEvents(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order events: %v", err)
+	}
+	return events, nil
+}
+
+// applyAmendment validates the amendment against the order and applies it.
+// It reports whether the order keeps its time priority.
+func applyAmendment(trade *domain.Trade, amendment domain.TradeAmendment) (bool, error) {
+	if !domain.CanTransition(trade.Status, trade.Status) {
+		return false, fmt.Errorf("%w: cannot amend a %s order", ErrInvalidTransition, trade.Status)
+	}
+
+	keepPriority := true
+	if amendment.Quantity != nil {
+		if *amendment.Quantity <= trade.FilledQuantity {
+			return false, fmt.Errorf("%w: quantity must exceed the filled quantity %.4f", ErrInvalidTrade, trade.FilledQuantity)
+		}
+		keepPriority = *amendment.Quantity < trade.Quantity
+		trade.Quantity = *amendment.Quantity
+	}
+	if amendment.Price != nil {
+		if trade.Type != domain.OrderTypeLimit && trade.Type != domain.OrderTypeStopLimit && trade.Type != domain.OrderTypeTakeProfit {
+			return false, fmt.Errorf("%w: only limit, stop_limit and take_profit orders have a price", ErrInvalidTrade)
+		}
+		if *amendment.Price <= 0 {
+			return false, fmt.Errorf("%w: price must be positive", ErrInvalidTrade)
+		}
+		if *amendment.Price != trade.Price {
+			keepPriority = false
+		}
+		trade.Price = *amendment.Price
+	}
+	if amendment.StopPrice != nil {
+		if !domain.IsConditional(trade.Type) || trade.Type == domain.OrderTypeTrailingStop {
+			return false, fmt.Errorf("%w: only stop, stop_limit and take_profit orders have a settable stop_price", ErrInvalidTrade)
+		}
+		if *amendment.StopPrice <= 0 {
+			return false, fmt.Errorf("%w: stop_price must be positive", ErrInvalidTrade)
+		}
+		trade.StopPrice = *amendment.StopPrice
+	}
+
+	// Conditional and queued orders are not on the book, so there is
+	// nothing to match
+	if domain.IsConditional(trade.Type) || trade.ActivatesAt != nil {
+		keepPriority = true
+	}
+
+	return keepPriority, nil
+}
+
+// orderError passes the usecase's own errors through and wraps anything else.
+func orderError(action string, err error) error {
+	for _, known := range []error{ErrTradeNotFound, ErrInvalidTrade, ErrTradeRejected, ErrInvalidTransition, ErrMarketDataUnavailable, ErrMarketClosed, ErrTradingHalted} {
+		if errors.Is(err, known) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to %s order: %v", action, err)
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+)
+
+func TestCancelOrderRecordsTransitions(t *testing.T) {
+	db := setupTestDB(t)
+	repo := memory.NewTradeRepository(db)
+	uc := newTestUsecase(t, repo, setupTestServices(t, 100))
+
+	sell, _, err := uc.PlaceTrade(context.Background(), "bob@example.com", limitOrder(domain.SideSell, 1, 90))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := uc.CancelOrder("alice@example.com", sell.ID); !errors.Is(err, ErrTradeNotFound) {
+		t.Errorf("expected other users to get ErrTradeNotFound, got %v", err)
+	}
+
+	cancelled, err := uc.CancelOrder("bob@example.com", sell.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cancelled.Status != domain.TradeStatusCancelled {
+		t.Errorf("expected cancelled status, got %s", cancelled.Status)
+	}
+
+	if _, err := uc.CancelOrder("bob@example.com", sell.ID); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected cancelling twice to fail, got %v", err)
+	}
+
+	events, err := repo.ListEvents(sell.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{domain.TradeStatusNew, domain.TradeStatusAccepted, domain.TradeStatusCancelled}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i, status := range want {
+		if events[i].ToStatus != status {
+			t.Errorf("event %d: expected %s, got %s", i, status, events[i].ToStatus)
+		}
+	}
+
+	// The cancelled order no longer rests on the book
+	buy := domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 1}
+	if _, executions, err := uc.PlaceTrade(context.Background(), "alice@example.com", buy); err != nil || len(executions) != 0 {
+		t.Errorf("expected no fills against a cancelled order, got %v %v", executions, err)
+	}
+}
+
+func TestAmendOrderRematchesOnPriceChange(t *testing.T) {
+	db := setupTestDB(t)
+	uc := newTestUsecase(t, memory.NewTradeRepository(db), setupTestServices(t, 100))
+
+	if _, _, err := uc.PlaceTrade(context.Background(), "bob@example.com", limitOrder(domain.SideSell, 1, 95)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buy, _, err := uc.PlaceTrade(context.Background(), "alice@example.com", limitOrder(domain.SideBuy, 2, 90))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	price := 95.0
+	amended, executions, err := uc.AmendOrder(context.Background(), "alice@example.com", buy.ID, domain.TradeAmendment{Price: &price})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executions) != 1 || amended.Status != domain.TradeStatusPartiallyFilled {
+		t.Fatalf("expected the amended order to partially fill, got %+v %+v", amended, executions)
+	}
+
+	quantity := 1.0
+	if _, _, err := uc.AmendOrder(context.Background(), "alice@example.com", buy.ID, domain.TradeAmendment{Quantity: &quantity}); !errors.Is(err, ErrInvalidTrade) {
+		t.Errorf("expected amending to the filled quantity to fail, got %v", err)
+	}
+
+	low := 10.0
+	if _, _, err := uc.AmendOrder(context.Background(), "alice@example.com", buy.ID, domain.TradeAmendment{Price: &low}); !errors.Is(err, ErrTradeRejected) {
+		t.Errorf("expected a price below the limit to be rejected, got %v", err)
+	}
+}
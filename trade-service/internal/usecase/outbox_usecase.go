@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	outboxPollInterval  = time.Second
+	outboxPruneInterval = time.Hour
+	// outboxBatchSize is the most events delivered to a webhook per poll
+	outboxBatchSize = 100
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+	// delivery body keyed with the webhook's secret.
+	SignatureHeader = "X-Signature-256"
+)
+
+var (
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// OutboxMessage is the body of a webhook delivery.
+type OutboxMessage struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// OutboxUsecase delivers the outbox events to the registered webhooks. Each
+// webhook gets every event created after it was registered, in order and at
+// least once: an event is retried with exponential backoff until the
+// webhook answers with a 2xx status, and the events after it wait.
+type OutboxUsecase struct {
+	repo   *memory.OutboxRepository
+	cfg    *config.Config
+	client *http.Client
+}
+
+func NewOutboxUsecase(repo *memory.OutboxRepository, cfg *config.Config) *OutboxUsecase {
+	return &OutboxUsecase{repo: repo, cfg: cfg, client: &http.Client{Timeout: cfg.WebhookTimeout}}
+}
+
+// RegisterWebhook adds a webhook. Without a secret one is generated; the
+// returned webhook is the only place it can be read.
+func (uc *OutboxUsecase) RegisterWebhook(createdBy, rawURL, secret string) (*domain.Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %v", err)
+		}
+		secret = hex.EncodeToString(key)
+	}
+
+	// Delivery starts with the events after the newest one
+	cursor, err := uc.repo.LastEventID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %v", err)
+	}
+	webhook := &domain.Webhook{URL: u.String(), Secret: secret, Cursor: cursor, CreatedBy: createdBy}
+	if err := uc.repo.InsertWebhook(webhook); err != nil {
+		return nil, fmt.Errorf("failed to save webhook: %v", err)
+	}
+
+	fmt.Printf("Webhook registered: %d %s by %s\n", webhook.ID, webhook.URL, createdBy)
+
+	return webhook, nil
+}
+
+// ListWebhooks returns the webhooks with the number of events each has yet
+// to receive.
+func (uc *OutboxUsecase) ListWebhooks() ([]domain.Webhook, error) {
+	webhooks, err := uc.repo.ListWebhooks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	for i := range webhooks {
+		if webhooks[i].Pending, err = uc.repo.CountEventsAfter(webhooks[i].Cursor); err != nil {
+			return nil, fmt.Errorf("failed to count pending events: %v", err)
+		}
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook stops deliveries to a webhook.
+func (uc *OutboxUsecase) DeleteWebhook(id uint) (*domain.Webhook, error) {
+	webhook, err := uc.repo.GetWebhook(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %v", err)
+	}
+	if err := uc.repo.DeleteWebhook(id); err != nil {
+		return nil, fmt.Errorf("failed to delete webhook: %v", err)
+	}
+
+	fmt.Printf("Webhook deleted: %d %s\n", webhook.ID, webhook.URL)
+
+	return webhook, nil
+}
+
+// Run delivers the outbox events and prunes the delivered ones, until ctx
+// is done.
+func (uc *OutboxUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := uc.dispatch(ctx, now); err != nil {
+				log.Println("Failed to dispatch outbox:", err)
+			}
+			if now.Sub(pruned) >= outboxPruneInterval {
+				if err := uc.prune(now); err != nil {
+					log.Println("Failed to prune outbox:", err)
+				}
+				pruned = now
+			}
+		}
+	}
+}
+
+// dispatch delivers the pending events to every webhook that is not
+// waiting for a retry.
+func (uc *OutboxUsecase) dispatch(ctx context.Context, now time.Time) error {
+	webhooks, err := uc.repo.ListWebhooks()
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if webhook.NextAttemptAt != nil && now.Before(*webhook.NextAttemptAt) {
+			continue
+		}
+		if err := uc.deliver(ctx, webhook, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver sends the webhook its pending events in order, up to the first
+// failure, and stores how far it got.
+func (uc *OutboxUsecase) deliver(ctx context.Context, webhook *domain.Webhook, now time.Time) error {
+	events, err := uc.repo.ListEventsAfter(webhook.Cursor, outboxBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list outbox events: %v", err)
+	}
+	for _, event := range events {
+		if err := uc.post(ctx, webhook, event); err != nil {
+			webhook.Attempts++
+			next := now.Add(uc.backoff(webhook.Attempts))
+			webhook.NextAttemptAt = &next
+			webhook.LastError = err.Error()
+			log.Printf("Webhook %d failed to receive event %d (attempt %d): %v", webhook.ID, event.ID, webhook.Attempts, err)
+			if err := uc.repo.SaveDelivery(webhook); err != nil {
+				return fmt.Errorf("failed to save webhook delivery: %v", err)
+			}
+			return nil
+		}
+
+		webhook.Cursor = event.ID
+		webhook.Attempts = 0
+		webhook.NextAttemptAt = nil
+		webhook.LastError = ""
+		if err := uc.repo.SaveDelivery(webhook); err != nil {
+			return fmt.Errorf("failed to save webhook delivery: %v", err)
+		}
+	}
+	return nil
+}
+
+// post sends one event to the webhook.
+func (uc *OutboxUsecase) post(ctx context.Context, webhook *domain.Webhook, event domain.OutboxEvent) error {
+	body, err := json.Marshal(OutboxMessage{ID: event.ID, Type: event.Type, CreatedAt: event.CreatedAt, Data: json.RawMessage(event.Payload)})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", fmt.Sprint(event.ID))
+	req.Header.Set("X-Event-Type", event.Type)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+
+	resp, err := uc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// backoff is the delay before the given retry: WebhookRetryMin doubled for
+// every earlier failure, at most WebhookRetryMax.
+func (uc *OutboxUsecase) backoff(attempts int) time.Duration {
+	delay := uc.cfg.WebhookRetryMin
+	for i := 1; i < attempts && delay < uc.cfg.WebhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, uc.cfg.WebhookRetryMax)
+}
+
+// prune deletes the events every webhook has received once they are older
+// than OutboxRetention.
+func (uc *OutboxUsecase) prune(now time.Time) error {
+	upTo, err := uc.repo.LastEventID()
+	if err != nil {
+		return fmt.Errorf("failed to read outbox: %v", err)
+	}
+	webhooks, err := uc.repo.ListWebhooks()
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	for _, webhook := range webhooks {
+		upTo = min(upTo, webhook.Cursor)
+	}
+	if _, err := uc.repo.PruneEvents(upTo, now.Add(-uc.cfg.OutboxRetention)); err != nil {
+		return fmt.Errorf("failed to prune outbox: %v", err)
+	}
+	return nil
+}
+
+// Sign returns the signature header value of a delivery body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutboxDeliversSignedEvents(t *testing.T) {
+	cfg := setupTestServices(t, 100)
+	cfg.WebhookTimeout = time.Second
+	cfg.WebhookRetryMin = time.Second
+	cfg.WebhookRetryMax = time.Minute
+	db := setupTestDB(t)
+	uc := newTestUsecase(t, memory.NewTradeRepository(db), cfg)
+	outbox := NewOutboxUsecase(memory.NewOutboxRepository(db), cfg)
+	ctx := context.Background()
+
+	// Events from before the webhook was registered are not delivered
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 1, 100))
+
+	var received []OutboxMessage
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != Sign("secret", body) {
+			t.Errorf("bad signature %q", r.Header.Get(SignatureHeader))
+		}
+		if fail {
+			fail = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var msg OutboxMessage
+		json.Unmarshal(body, &msg)
+		received = append(received, msg)
+	}))
+	defer server.Close()
+
+	webhook, err := outbox.RegisterWebhook("admin@example.com", server.URL, "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 1, 100)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first attempt fails and the retry waits for the backoff
+	now := time.Now()
+	outbox.dispatch(ctx, now)
+	outbox.dispatch(ctx, now.Add(500*time.Millisecond))
+	if len(received) != 0 {
+		t.Fatalf("expected no delivery before the retry, got %d", len(received))
+	}
+	webhooks, _ := outbox.ListWebhooks()
+	if webhooks[0].Attempts != 1 || webhooks[0].LastError == "" || webhooks[0].Pending == 0 {
+		t.Errorf("expected a failed attempt recorded, got %+v", webhooks[0])
+	}
+
+	outbox.dispatch(ctx, now.Add(2*time.Second))
+	var types []string
+	for _, msg := range received {
+		types = append(types, msg.Type)
+	}
+	want := []string{"order.new", "order.accepted", "order.filled", "order.filled", "execution.created"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("expected events %v, got %v", want, types)
+	}
+	webhooks, _ = outbox.ListWebhooks()
+	if webhooks[0].ID != webhook.ID || webhooks[0].Attempts != 0 || webhooks[0].Pending != 0 {
+		t.Errorf("expected every event delivered, got %+v", webhooks[0])
+	}
+}
@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"simpletrading/tradeservice/internal/calendar"
+	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/matching"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"simpletrading/tradeservice/internal/risk"
+	"strings"
+)
+
+var ErrInvalidTradingMode = errors.New("invalid trading mode")
+
+// NewPaperTradeUsecase creates a usecase for paper trading. It runs orders
+// through the same validation, risk rules, fees, calendar, halts and
+// matching as the live one, but against its own repository and order book,
+// which must not be shared with the live usecase.
+func NewPaperTradeUsecase(repo *memory.TradeRepository, engine *matching.Engine, rules *risk.Engine, fees *fees.Engine, cal *calendar.Engine, halts *HaltUsecase, prices PriceSource, cfg *config.Config) *TradeUsecase {
+	uc := NewTradeUsecase(repo, engine, rules, fees, cal, halts, prices, cfg)
+	uc.paper = true
+	return uc
+}
+
+// Mode returns the trading mode the usecase trades in.
+func (uc *TradeUsecase) Mode() string {
+	if uc.paper {
+		return domain.TradingModePaper
+	}
+	return domain.TradingModeLive
+}
+
+// TradingMode returns the mode the user's orders go to: paper if paper
+// trading is on globally or for the user's account, live otherwise.
+func (uc *TradeUsecase) TradingMode(userID string) (string, error) {
+	if uc.cfg.PaperTrading {
+		return domain.TradingModePaper, nil
+	}
+	settings, err := uc.repo.GetSettings(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load account settings: %v", err)
+	}
+	return settings.TradingMode, nil
+}
+
+// SetTradingMode switches the user's account between live and paper
+// trading; paper is the paper trading usecase. The switch is refused while
+// the account has open orders, working algo orders or active order groups
+// in its current mode, since they could no longer be seen or cancelled.
+// While paper trading is on globally, it has no effect until it is turned
+// off.
+func (uc *TradeUsecase) SetTradingMode(paper *TradeUsecase, userID, mode string) error {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode != domain.TradingModeLive && mode != domain.TradingModePaper {
+		return fmt.Errorf("%w: mode must be live or paper", ErrInvalidTradingMode)
+	}
+
+	settings, err := uc.repo.GetSettings(userID)
+	if err != nil {
+		return fmt.Errorf("failed to load account settings: %v", err)
+	}
+	if settings.TradingMode != mode {
+		current := uc
+		if settings.TradingMode == domain.TradingModePaper {
+			current = paper
+		}
+		open, err := current.repo.HasOpenOrders(userID)
+		if err != nil {
+			return fmt.Errorf("failed to load open orders: %v", err)
+		}
+		if open {
+			return fmt.Errorf("%w: cancel the open %s orders before switching to %s", ErrInvalidTransition, settings.TradingMode, mode)
+		}
+	}
+	settings.TradingMode = mode
+	if err := uc.repo.SaveSettings(settings); err != nil {
+		return fmt.Errorf("failed to save account settings: %v", err)
+	}
+
+	fmt.Printf("Trading mode: %s %s\n", userID, mode)
+
+	return nil
+}
+
+// FundPaperAccount gives a paper account its starting cash the first time
+// it is used. It does nothing for live accounts.
+func (uc *TradeUsecase) FundPaperAccount(userID string) error {
+	if !uc.paper || uc.cfg.PaperStartingCash <= 0 {
+		return nil
+	}
+
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	cash, err := uc.repo.CashBalances(domain.UserAccount(userID))
+	if err != nil {
+		return fmt.Errorf("failed to load balances: %v", err)
+	}
+	if len(cash) > 0 {
+		return nil
+	}
+
+	entry := domain.NewTransfer(domain.EntryDeposit, "paper-starting-cash", "paper trading starting cash",
+		domain.DefaultCurrency, domain.AccountExternal, domain.UserAccount(userID), uc.cfg.PaperStartingCash)
+	if err := uc.repo.PostEntry(&entry); err != nil {
+		return fmt.Errorf("failed to fund paper account: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpletrading/tradeservice/internal/domain"
+	"strings"
+)
+
+// GetPortfolio returns the user's positions marked against the latest
+// price. If no price is available the positions are returned unmarked.
+func (uc *TradeUsecase) GetPortfolio(ctx context.Context, userID string) (*domain.Portfolio, error) {
+	positions, err := uc.repo.ListPositions(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list positions: %v", err)
+	}
+
+	mark, marked := uc.markPrice(ctx)
+	portfolio := &domain.Portfolio{Positions: make([]domain.PositionValuation, 0, len(positions))}
+	var unrealized float64
+	for _, p := range positions {
+		valuation := domain.NewPositionValuation(p, mark, marked)
+		portfolio.Positions = append(portfolio.Positions, valuation)
+		portfolio.RealizedPnL += p.RealizedPnL
+		if marked {
+			unrealized += *valuation.UnrealizedPnL
+		}
+	}
+	if marked {
+		portfolio.UnrealizedPnL = &unrealized
+	}
+	return portfolio, nil
+}
+
+// GetPosition returns the user's position in a symbol marked against the
+// latest price. A symbol the user never traded gives a flat position.
+func (uc *TradeUsecase) GetPosition(ctx context.Context, userID, symbol string) (*domain.PositionValuation, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, fmt.Errorf("%w: symbol is required", ErrInvalidTrade)
+	}
+
+	position, err := uc.repo.GetPosition(userID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get position: %v", err)
+	}
+
+	mark, marked := uc.markPrice(ctx)
+	valuation := domain.NewPositionValuation(*position, mark, marked)
+	return &valuation, nil
+}
+
+// markPrice returns the latest Data Service price, or the 24h lowest if the
+// service does not report a last price.
+func (uc *TradeUsecase) markPrice(ctx context.Context) (float64, bool) {
+	stats, _, err := uc.prices.PriceStats(ctx)
+	if err != nil {
+		log.Println("Failed to get mark price:", err)
+		return 0, false
+	}
+	mark := stats.Mark()
+	return mark, mark > 0
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/risk"
+	"time"
+)
+
+// MarketHoursRuleID is reported by a preview when the order cannot be
+// placed outside its symbol's session.
+const MarketHoursRuleID = "market-hours"
+
+// TradingHaltRuleID is reported by a preview when a trading halt covers the
+// order.
+const TradingHaltRuleID = "trading-halt"
+
+// TradePreview is what placing an order would do right now.
+type TradePreview struct {
+	Trade          domain.Trade     `json:"trade"` // The order as it would be placed
+	ReferencePrice float64          `json:"reference_price"`
+	MinPrice       float64          `json:"min_price"`           // Lowest price the risk rules allow, zero if none
+	MaxPrice       float64          `json:"max_price,omitempty"` // Highest price the risk rules allow, zero if none
+	FillQuantity   float64          `json:"fill_quantity"`       // Matched against the book at once
+	AveragePrice   float64          `json:"average_price,omitempty"`
+	Rests          bool             `json:"rests"` // Whether the remainder would rest on the book
+	Fee            float64          `json:"fee"`   // Taker fee of the immediate fills plus maker fee of a resting remainder
+	Position       float64          `json:"position"`
+	PositionAfter  float64          `json:"position_after"`    // After the whole order fills
+	Balance        *domain.Balance  `json:"balance,omitempty"` // Cash in the quote currency, for buys
+	Violations     []risk.Violation `json:"violations"`        // Rules that would reject the order
+	Accepted       bool             `json:"accepted"`          // Whether PlaceTrade would accept the order
+	CheckedAt      time.Time        `json:"checked_at"`
+}
+
+// PreviewTrade runs the PlaceTrade checks against the order and estimates
+// its fills and fees without storing anything or touching the book.
+func (uc *TradeUsecase) PreviewTrade(ctx context.Context, userID string, trade domain.Trade) (*TradePreview, error) {
+	if err := ValidateTrade(&trade); err != nil {
+		return nil, err
+	}
+
+	market, err := uc.marketPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trade.UserID = userID
+	trade.ReferencePrice = market.Lowest
+	trade.Status = domain.TradeStatusNew
+	if trade.Type == domain.OrderTypeTrailingStop {
+		trade.Trail(market.Mark())
+	}
+
+	preview := &TradePreview{ReferencePrice: market.Lowest, Violations: []risk.Violation{}, CheckedAt: time.Now()}
+	if err := uc.halts.Check(userID, trade.Symbol); err != nil {
+		if !errors.Is(err, ErrTradingHalted) {
+			return nil, err
+		}
+		preview.Violations = append(preview.Violations, risk.Violation{RuleID: TradingHaltRuleID, Message: err.Error()})
+	}
+	if err := uc.scheduleTrade(&trade, preview.CheckedAt); err != nil {
+		if !errors.Is(err, ErrMarketClosed) {
+			return nil, err
+		}
+		preview.Violations = append(preview.Violations, risk.Violation{RuleID: MarketHoursRuleID, Message: err.Error()})
+	}
+	preview.Trade = trade
+	preview.MinPrice, preview.MaxPrice = uc.rules.PriceBounds(&trade, market)
+
+	// Same lock as PlaceTrade, so the checks see a consistent set of funds
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	if err := uc.checkRisk(&trade, market); err != nil {
+		var rejection *RejectionError
+		if !errors.As(err, &rejection) {
+			return nil, err
+		}
+		preview.Violations = append(preview.Violations, rejection.Violations...)
+	}
+	preview.Accepted = len(preview.Violations) == 0
+
+	position, err := uc.repo.NetPosition(userID, trade.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load position: %v", err)
+	}
+	preview.Position = position
+	preview.PositionAfter = position + trade.Quantity
+	if trade.Side == domain.SideSell {
+		preview.PositionAfter = position - trade.Quantity
+	}
+
+	if trade.Side == domain.SideBuy {
+		balance, err := uc.balance(userID, domain.QuoteCurrency(trade.Symbol), 0)
+		if err != nil {
+			return nil, err
+		}
+		preview.Balance = &balance
+	}
+
+	if err := uc.estimateFills(preview, market); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// estimateFills matches the previewed order against a snapshot of the book
+// and prices the fees. A conditional order is not matched until it triggers,
+// so its fee is the taker fee of a full fill at its stop price.
+func (uc *TradeUsecase) estimateFills(preview *TradePreview, market domain.PriceStats) error {
+	trade := &preview.Trade
+	volume, err := uc.repo.TradedNotional(trade.UserID, time.Now().Add(-feeVolumeWindow))
+	if err != nil {
+		return fmt.Errorf("failed to load 30-day volume: %v", err)
+	}
+	first := true
+	fee := func(notional float64, maker bool) float64 {
+		f := uc.fees.Fee(notional, volume, maker, first)
+		first = false
+		return f
+	}
+
+	if domain.IsConditional(trade.Type) {
+		preview.Fee = fee(risk.Notional(trade, market), false)
+		return nil
+	}
+
+	// A queued order does not match until the session opens
+	if trade.ActivatesAt != nil {
+		preview.Rests = true
+		preview.Fee = fee(trade.Quantity*trade.Price, true)
+		return nil
+	}
+
+	result := uc.engine.Preview(trade)
+	var notional float64
+	for _, e := range result.Executions {
+		preview.FillQuantity += e.Quantity
+		notional += e.Price * e.Quantity
+		preview.Fee += fee(e.Price*e.Quantity, false)
+	}
+	if preview.FillQuantity > 0 {
+		preview.AveragePrice = notional / preview.FillQuantity
+	}
+
+	preview.Rests = result.Rested
+	if result.Rested {
+		preview.Fee += fee((trade.Quantity-preview.FillQuantity)*trade.Price, true)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"context"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+)
+
+func TestPreviewTradeStoresNothing(t *testing.T) {
+	db := setupTestDB(t)
+	uc := newTestUsecase(t, memory.NewTradeRepository(db), setupTestServices(t, 100))
+	ctx := context.Background()
+
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 1, 100))
+
+	preview, err := uc.PreviewTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 3, 100))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !preview.Accepted || preview.MinPrice != 50 || preview.FillQuantity != 1 || !preview.Rests ||
+		preview.PositionAfter != 3 {
+		t.Errorf("unexpected preview: %+v", preview)
+	}
+
+	rejected, err := uc.PreviewTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 1, 40))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rejected.Accepted || len(rejected.Violations) != 1 || rejected.Violations[0].RuleID != "min-price-half-low" {
+		t.Errorf("expected the min price rule to reject the order, got %+v", rejected)
+	}
+
+	var count int64
+	db.Model(&domain.Trade{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected only bob's order stored, got %d orders", count)
+	}
+	if buy, _, _ := uc.PlaceTrade(ctx, "carol@example.com", limitOrder(domain.SideBuy, 1, 100)); buy.FilledQuantity != 1 {
+		t.Errorf("expected bob's order still on the book, got %+v", buy)
+	}
+}
@@ -0,0 +1,317 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportUsecase builds the end-of-day statements from the executions and
+// the ledger.
+type ReportUsecase struct {
+	repo *memory.TradeRepository
+}
+
+func NewReportUsecase(repo *memory.TradeRepository) *ReportUsecase {
+	return &ReportUsecase{repo: repo}
+}
+
+// DailyReport builds the statements of the UTC day containing date. With a
+// user ID it holds only that user's statement; without one it holds the
+// statement of every user with fills, cash movements, cash or an open
+// position, and the firm-wide summary.
+func (uc *ReportUsecase) DailyReport(date time.Time, userID string) (*domain.DailyReport, error) {
+	from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	// Positions are replayed from every fill up to the close; a single
+	// user's statement only reads that user's fills and postings
+	executions, err := uc.repo.ListUserExecutionsBefore(userID, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %v", err)
+	}
+	movements, err := uc.repo.ListMovements(userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cash movements: %v", err)
+	}
+	opening, err := uc.repo.SumPostingsBefore(userID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum opening cash: %v", err)
+	}
+
+	statements := make(map[string]*domain.DailyStatement)
+	statement := func(user string) *domain.DailyStatement {
+		s, ok := statements[user]
+		if !ok {
+			s = &domain.DailyStatement{UserID: user, Fills: []domain.StatementFill{},
+				Positions: []domain.StatementPosition{}, Cash: []domain.CashSummary{}, Movements: []domain.CashMovement{}}
+			statements[user] = s
+		}
+		return s
+	}
+
+	positions := make(map[string]map[string]*domain.Position) // By user and symbol
+	traded := make(map[string]map[string]bool)
+	for _, e := range executions {
+		for _, f := range statementFills(e) {
+			user := f.userID
+			if userID != "" && user != userID {
+				continue // The counterparty's side
+			}
+			if positions[user] == nil {
+				positions[user], traded[user] = make(map[string]*domain.Position), make(map[string]bool)
+			}
+			position, ok := positions[user][e.Symbol]
+			if !ok {
+				position = &domain.Position{UserID: user, Symbol: e.Symbol}
+				positions[user][e.Symbol] = position
+			}
+			position.Apply(f.Side, f.Quantity, f.Price)
+
+			if !e.CreatedAt.Before(from) {
+				s := statement(user)
+				s.Fills = append(s.Fills, f.StatementFill)
+				traded[user][e.Symbol] = true
+			}
+		}
+	}
+
+	// Cash per user and currency: what was there at the open plus the day's
+	// postings
+	cash := make(map[string]map[string]*domain.CashSummary)
+	summary := func(user, currency string) *domain.CashSummary {
+		if cash[user] == nil {
+			cash[user] = make(map[string]*domain.CashSummary)
+		}
+		c, ok := cash[user][currency]
+		if !ok {
+			c = &domain.CashSummary{Currency: currency}
+			cash[user][currency] = c
+		}
+		return c
+	}
+	for account, balances := range opening {
+		user, ok := strings.CutPrefix(account, domain.UserAccount(""))
+		if !ok {
+			continue
+		}
+		for currency, total := range balances {
+			c := summary(user, currency)
+			c.Opening, c.Closing = total, total
+		}
+	}
+	for _, m := range movements {
+		user, ok := strings.CutPrefix(m.Account, domain.UserAccount(""))
+		if !ok {
+			continue
+		}
+		s := statement(user)
+		s.Movements = append(s.Movements, m)
+		c := summary(user, m.Currency)
+		switch m.Kind {
+		case domain.EntryDeposit:
+			c.Deposits += m.Amount
+		case domain.EntryWithdrawal:
+			c.Withdrawals += m.Amount
+		case domain.EntrySettlement:
+			c.Trading += m.Amount
+		case domain.EntryFee:
+			c.Fees += m.Amount
+		}
+		c.Closing += m.Amount
+	}
+
+	// Users without activity still get a statement while they hold cash or
+	// a position
+	for user, balances := range cash {
+		for _, c := range balances {
+			if !nearZero(c.Closing) {
+				statement(user)
+			}
+		}
+	}
+	for user, symbols := range positions {
+		for _, p := range symbols {
+			if !nearZero(p.Quantity) {
+				statement(user)
+			}
+		}
+	}
+
+	report := &domain.DailyReport{Date: from.Format(time.DateOnly), From: from, To: to,
+		Statements: []domain.DailyStatement{}, GeneratedAt: time.Now()}
+	for user, s := range statements {
+		if userID != "" && user != userID {
+			continue
+		}
+		for _, symbol := range sortedKeys(positions[user]) {
+			p := positions[user][symbol]
+			if nearZero(p.Quantity) && !traded[user][symbol] {
+				continue
+			}
+			s.Positions = append(s.Positions, domain.StatementPosition{
+				Symbol: symbol, Quantity: p.Quantity, AverageCost: p.AverageCost, RealizedPnL: p.RealizedPnL,
+			})
+		}
+		for _, currency := range sortedKeys(cash[user]) {
+			s.Cash = append(s.Cash, *cash[user][currency])
+		}
+		report.Statements = append(report.Statements, *s)
+	}
+	sort.Slice(report.Statements, func(i, j int) bool { return report.Statements[i].UserID < report.Statements[j].UserID })
+
+	if userID == "" {
+		report.Summary = summarize(report.Statements, executions, from)
+	}
+	return report, nil
+}
+
+// userFill is a fill on one side of an execution.
+type userFill struct {
+	domain.StatementFill
+	userID string
+}
+
+// statementFills splits an execution into the buyer's and the seller's
+// fill.
+func statementFills(e domain.Execution) []userFill {
+	notional := e.Price * e.Quantity
+	fill := domain.StatementFill{ExecutionID: e.ID, Symbol: e.Symbol, Quantity: e.Quantity, Price: e.Price,
+		Notional: notional, Currency: domain.QuoteCurrency(e.Symbol), Time: e.CreatedAt}
+
+	buy, sell := fill, fill
+	buy.TradeID, buy.Side, buy.Fee = e.BuyTradeID, domain.SideBuy, e.BuyFee
+	sell.TradeID, sell.Side, sell.Fee = e.SellTradeID, domain.SideSell, e.SellFee
+	buy.Liquidity, sell.Liquidity = "maker", "taker"
+	if e.TakerSide == domain.SideBuy {
+		buy.Liquidity, sell.Liquidity = "taker", "maker"
+	}
+	return []userFill{{buy, e.BuyUserID}, {sell, e.SellUserID}}
+}
+
+// summarize totals the statements into the firm-wide summary.
+func summarize(statements []domain.DailyStatement, executions []domain.Execution, from time.Time) *domain.DailySummary {
+	summary := &domain.DailySummary{Users: len(statements), Volume: []domain.SymbolVolume{}, Cash: []domain.CashSummary{}}
+
+	volumes := make(map[string]*domain.SymbolVolume)
+	for _, e := range executions {
+		if e.CreatedAt.Before(from) {
+			continue
+		}
+		v, ok := volumes[e.Symbol]
+		if !ok {
+			v = &domain.SymbolVolume{Symbol: e.Symbol}
+			volumes[e.Symbol] = v
+		}
+		v.Executions++
+		v.Quantity += e.Quantity
+		v.Notional += e.Price * e.Quantity
+		v.Fees += e.BuyFee + e.SellFee
+		summary.Executions++
+	}
+	for _, symbol := range sortedKeys(volumes) {
+		summary.Volume = append(summary.Volume, *volumes[symbol])
+	}
+
+	cash := make(map[string]*domain.CashSummary)
+	for _, s := range statements {
+		for _, c := range s.Cash {
+			total, ok := cash[c.Currency]
+			if !ok {
+				total = &domain.CashSummary{Currency: c.Currency}
+				cash[c.Currency] = total
+			}
+			total.Opening += c.Opening
+			total.Deposits += c.Deposits
+			total.Withdrawals += c.Withdrawals
+			total.Trading += c.Trading
+			total.Fees += c.Fees
+			total.Closing += c.Closing
+		}
+	}
+	for _, currency := range sortedKeys(cash) {
+		summary.Cash = append(summary.Cash, *cash[currency])
+	}
+	return summary
+}
+
+// reportColumns is the header of the CSV report. Every row is one record of
+// a statement or of the summary, whose rows have no user ID.
+var reportColumns = []string{"date", "user_id", "record", "reference", "symbol", "side", "quantity", "price", "notional", "fee", "currency", "amount", "time"}
+
+// WriteDailyReportCSV writes the report as CSV. Fills are "fill" records,
+// positions "position" records (price is the average cost, amount the
+// realized P&L), cash movements carry their journal entry kind, and each
+// currency's cash has "opening_cash" and "closing_cash" records. The
+// summary adds a "volume" record per symbol and a record per currency for
+// each line of its cash summary.
+func WriteDailyReportCSV(w io.Writer, report *domain.DailyReport) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(reportColumns); err != nil {
+		return err
+	}
+	row := func(user, record, reference, symbol, side, quantity, price, notional, fee, currency, amount string, at time.Time) {
+		stamp := ""
+		if !at.IsZero() {
+			stamp = at.UTC().Format(time.RFC3339)
+		}
+		out.Write([]string{report.Date, user, record, reference, symbol, side, quantity, price, notional, fee, currency, amount, stamp})
+	}
+
+	for _, s := range report.Statements {
+		for _, f := range s.Fills {
+			row(s.UserID, "fill", fmt.Sprintf("execution:%d", f.ExecutionID), f.Symbol, f.Side,
+				number(f.Quantity), number(f.Price), number(f.Notional), number(f.Fee), f.Currency, "", f.Time)
+		}
+		for _, p := range s.Positions {
+			row(s.UserID, "position", "", p.Symbol, "", number(p.Quantity), number(p.AverageCost), "", "", "", number(p.RealizedPnL), time.Time{})
+		}
+		for _, m := range s.Movements {
+			row(s.UserID, m.Kind, m.Reference, "", "", "", "", "", "", m.Currency, number(m.Amount), m.Time)
+		}
+		for _, c := range s.Cash {
+			row(s.UserID, "opening_cash", "", "", "", "", "", "", "", c.Currency, number(c.Opening), time.Time{})
+			row(s.UserID, "closing_cash", "", "", "", "", "", "", "", c.Currency, number(c.Closing), time.Time{})
+		}
+	}
+
+	if report.Summary != nil {
+		for _, v := range report.Summary.Volume {
+			row("", "volume", "", v.Symbol, "", number(v.Quantity), "", number(v.Notional), number(v.Fees), "", "", time.Time{})
+		}
+		for _, c := range report.Summary.Cash {
+			for _, total := range []struct {
+				record string
+				amount float64
+			}{{"opening_cash", c.Opening}, {"deposits", c.Deposits}, {"withdrawals", c.Withdrawals}, {"trading", c.Trading}, {"fees", c.Fees}, {"closing_cash", c.Closing}} {
+				row("", total.record, "", "", "", "", "", "", "", c.Currency, number(total.amount), time.Time{})
+			}
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func nearZero(v float64) bool {
+	return v < 1e-9 && v > -1e-9
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"reflect"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestDailyReport(t *testing.T) {
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, setupTestServices(t, 100))
+	var err error
+	if uc.fees, err = fees.NewEngineWithSchedule(fees.Schedule{Flat: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	uc.Deposit("alice@example.com", "USD", 1000, "wire-1")
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 3, 100))
+	uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 2, 100))
+
+	reports := NewReportUsecase(repo)
+	report, err := reports.DailyReport(time.Now().UTC(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Statements) != 2 || report.Summary == nil || report.Summary.Executions != 1 {
+		t.Fatalf("expected alice's and bob's statements and one execution, got %+v", report)
+	}
+
+	alice := report.Statements[0]
+	if len(alice.Fills) != 1 || alice.Fills[0].Side != domain.SideBuy || alice.Fills[0].Fee != 1 || alice.Fills[0].Liquidity != "taker" {
+		t.Errorf("unexpected fills: %+v", alice.Fills)
+	}
+	if len(alice.Positions) != 1 || alice.Positions[0].Quantity != 2 || alice.Positions[0].AverageCost != 100 {
+		t.Errorf("unexpected positions: %+v", alice.Positions)
+	}
+	want := domain.CashSummary{Currency: "USD", Deposits: 1000, Trading: -200, Fees: -1, Closing: 799}
+	if len(alice.Cash) != 1 || alice.Cash[0] != want || len(alice.Movements) != 3 {
+		t.Errorf("expected %+v from 3 movements, got %+v, %+v", want, alice.Cash, alice.Movements)
+	}
+	if v := report.Summary.Volume; len(v) != 1 || v[0].Notional != 200 || v[0].Fees != 2 {
+		t.Errorf("unexpected volume: %+v", v)
+	}
+
+	// Read for one user, the statement is the same as in the full report
+	mine, err := reports.DailyReport(time.Now().UTC(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mine.Statements) != 1 || !reflect.DeepEqual(mine.Statements[0], alice) {
+		t.Errorf("expected %+v, got %+v", alice, mine.Statements)
+	}
+
+	// A user only sees their own statement; the next day opens at the close
+	own, _ := reports.DailyReport(time.Now().UTC().AddDate(0, 0, 1), "alice@example.com")
+	if len(own.Statements) != 1 || own.Summary != nil || own.Statements[0].Cash[0].Opening != 799 || len(own.Statements[0].Fills) != 0 {
+		t.Errorf("unexpected next day statement: %+v", own)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDailyReportCSV(&buf, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	records := make(map[string]int)
+	for _, row := range rows[1:] {
+		records[row[2]]++
+	}
+	if records["fill"] != 2 || records["deposit"] != 1 || records["volume"] != 1 {
+		t.Errorf("unexpected CSV records: %v", records)
+	}
+}
@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/matching"
+	"time"
+)
+
+const sessionPollInterval = time.Second
+
+// errNotDue means an order changed before its session event could be
+// applied: it was filled, cancelled or released already.
+var errNotDue = errors.New("order no longer due")
+
+// scheduleTrade fits the order to its symbol's trading session. Outside the
+// session, limit GTC and DAY orders are queued for the next open and other
+// orders are refused; conditional orders wait for their trigger either way.
+// A DAY order expires at the close of the session it trades in.
+func (uc *TradeUsecase) scheduleTrade(trade *domain.Trade, now time.Time) error {
+	session, ok := uc.calendar.Session(trade.Symbol, now)
+	if !ok {
+		return fmt.Errorf("%w: no session scheduled for %s", ErrMarketClosed, trade.Symbol)
+	}
+
+	if !session.Contains(now) && !domain.IsConditional(trade.Type) {
+		if trade.Type != domain.OrderTypeLimit ||
+			(trade.TimeInForce != domain.TimeInForceGTC && trade.TimeInForce != domain.TimeInForceDAY) {
+			return fmt.Errorf("%w: %s opens at %s; only GTC and DAY limit orders can wait for the open",
+				ErrMarketClosed, trade.Symbol, session.Open.UTC().Format(time.RFC3339))
+		}
+		open := session.Open
+		trade.ActivatesAt = &open
+	}
+
+	if trade.TimeInForce == domain.TimeInForceDAY {
+		close := session.Close
+		trade.ExpiresAt = &close
+	}
+	return nil
+}
+
+// RunSessions expires DAY orders at their session close and sends queued
+// orders to the book when their session opens, until ctx is done.
+func (uc *TradeUsecase) RunSessions(ctx context.Context) {
+	ticker := time.NewTicker(sessionPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := uc.checkSessions(now); err != nil {
+				log.Println("Failed to check trading sessions:", err)
+			}
+		}
+	}
+}
+
+// checkSessions applies the session closes and opens due at the given
+// time. Expiry runs first, so a queued DAY order whose session has passed
+// expires instead of trading. Halted orders stay queued. An order that fails
+// does not hold up the others; the errors are returned together.
+func (uc *TradeUsecase) checkSessions(now time.Time) error {
+	var errs []error
+	expiring, err := uc.repo.ListExpiring(now)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list expiring orders: %v", err))
+	}
+	errs = append(errs, uc.expireOrders(expiring, now))
+
+	queued, err := uc.repo.ListQueued()
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("failed to list queued orders: %v", err))...)
+	}
+	errs = append(errs, uc.activateOrders(queued, now))
+	return errors.Join(errs...)
+}
+
+// expireOrders expires the given DAY orders, returning the errors of those
+// that failed together.
+func (uc *TradeUsecase) expireOrders(orders []domain.Trade, now time.Time) error {
+	var errs []error
+	for i := range orders {
+		if err := uc.expire(&orders[i], now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// activateOrders sends the given queued orders to the book if their market
+// is open and they are not halted, returning the errors of those that
+// failed together.
+func (uc *TradeUsecase) activateOrders(orders []domain.Trade, now time.Time) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	halts, err := uc.halts.ListActive()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range orders {
+		if !uc.calendar.IsOpen(orders[i].Symbol, now) || haltError(halts, orders[i].UserID, orders[i].Symbol) != nil {
+			continue
+		}
+		if err := uc.activate(&orders[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// expire takes a DAY order past its session close off the book.
+func (uc *TradeUsecase) expire(order *domain.Trade, now time.Time) error {
+	var trade *domain.Trade
+	err := uc.engine.Cancel(order.Symbol, order.ID, func() error {
+		// Re-read with the book locked so a fill that raced the check is seen
+		current, err := uc.repo.GetByID(order.ID)
+		if err != nil {
+			return err
+		}
+		if !domain.IsOpen(current.Status) || current.ExpiresAt == nil || current.ExpiresAt.After(now) {
+			return errNotDue
+		}
+
+		trade = current
+		from := trade.Status
+		trade.Status = domain.TradeStatusExpired
+		trade.ActivatesAt = nil
+		return uc.repo.Update(trade, domain.NewOrderEvent(trade, from, "DAY order expired at session close"))
+	})
+	if errors.Is(err, errNotDue) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to expire trade %d: %v", order.ID, err)
+	}
+
+	fmt.Printf("Trade expired: %d\n", trade.ID)
+
+	return nil
+}
+
+// activate sends an order queued for its session to the book, where it is
+// matched like a new order.
+func (uc *TradeUsecase) activate(order *domain.Trade) error {
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	var trade *domain.Trade
+	var from string
+	var opened domain.OrderEvent
+	_, err := uc.engine.Amend(order.Symbol, order.ID, func() (*domain.Trade, bool, error) {
+		// Re-read with the book locked so a cancel or amend that raced the
+		// check is seen
+		current, err := uc.repo.GetByID(order.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		if !domain.IsOpen(current.Status) || current.ActivatesAt == nil {
+			return nil, false, errNotDue
+		}
+
+		trade = current
+		from = trade.Status
+		trade.ActivatesAt = nil
+		opened = domain.NewOrderEvent(trade, from, "session opened")
+		return trade, false, nil
+	}, func(result *matching.Result) error {
+		if err := uc.chargeFees(trade, result); err != nil {
+			return err
+		}
+		events := append([]domain.OrderEvent{opened}, matchEvents(trade, from, result)...)
+		return uc.saveMatch(trade, result, events)
+	})
+	if errors.Is(err, errNotDue) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to activate trade %d: %v", order.ID, err)
+	}
+
+	fmt.Printf("Trade activated: %d %s at session open, filled %.4f\n", trade.ID, trade.Symbol, trade.FilledQuantity)
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"simpletrading/tradeservice/internal/calendar"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestOrdersWaitForTheSession(t *testing.T) {
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, setupTestServices(t, 100))
+	ctx := context.Background()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	sessions, err := calendar.NewEngineWithCalendar(calendar.Calendar{Symbols: map[string]calendar.Schedule{
+		"BTCUSD": {Holidays: []string{today.Format(time.DateOnly)}},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uc.calendar = sessions
+
+	ioc := limitOrder(domain.SideBuy, 1, 100)
+	ioc.TimeInForce = domain.TimeInForceIOC
+	if _, _, err := uc.PlaceTrade(ctx, "alice@example.com", ioc); !errors.Is(err, ErrMarketClosed) {
+		t.Fatalf("expected an IOC order to be refused while closed, got %v", err)
+	}
+
+	day := limitOrder(domain.SideBuy, 2, 100)
+	day.TimeInForce = domain.TimeInForceDAY
+	buy, _, err := uc.PlaceTrade(ctx, "alice@example.com", day)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tomorrow := today.AddDate(0, 0, 1)
+	if buy.ActivatesAt == nil || !buy.ActivatesAt.Equal(tomorrow) || buy.ExpiresAt == nil || !buy.ExpiresAt.Equal(tomorrow.AddDate(0, 0, 1)) {
+		t.Fatalf("expected the DAY order queued for tomorrow's session, got %+v", buy)
+	}
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 1, 100))
+	if open, _ := repo.ListOpen(); len(open) != 0 {
+		t.Fatalf("expected queued orders off the book, got %+v", open)
+	}
+
+	// At the open both orders reach the book and match
+	if err := uc.checkSessions(tomorrow.Add(time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buy, _ = repo.GetByID(buy.ID); buy.Status != domain.TradeStatusPartiallyFilled || buy.ActivatesAt != nil {
+		t.Fatalf("expected the DAY order to trade at the open, got %+v", buy)
+	}
+
+	if err := uc.checkSessions(tomorrow.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buy, _ = repo.GetByID(buy.ID); buy.Status != domain.TradeStatusExpired {
+		t.Errorf("expected the DAY order to expire at the close, got %+v", buy)
+	}
+}
+
+func TestFailedSessionOrderDoesNotBlockOthers(t *testing.T) {
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, setupTestServices(t, 100))
+	ctx := context.Background()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	sessions, err := calendar.NewEngineWithCalendar(calendar.Calendar{Symbols: map[string]calendar.Schedule{
+		"BTCUSD": {Holidays: []string{today.Format(time.DateOnly)}},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uc.calendar = sessions
+
+	day := limitOrder(domain.SideBuy, 1, 100)
+	day.TimeInForce = domain.TimeInForceDAY
+	queued, _, err := uc.PlaceTrade(ctx, "alice@example.com", day)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An order that cannot be activated comes first
+	tomorrow := today.AddDate(0, 0, 1)
+	waiting, _ := repo.ListQueued()
+	broken := waiting[0]
+	broken.ID = 9999
+	if err := uc.activateOrders(append([]domain.Trade{broken}, waiting...), tomorrow.Add(time.Second)); err == nil {
+		t.Error("expected the failed activation to be reported")
+	}
+	if activated, _ := repo.GetByID(queued.ID); activated.ActivatesAt != nil {
+		t.Fatalf("expected the queued order activated anyway, got %+v", activated)
+	}
+
+	// Likewise for an order that cannot be expired
+	expiring, _ := repo.ListExpiring(tomorrow.AddDate(0, 0, 1))
+	if err := uc.expireOrders(append([]domain.Trade{broken}, expiring...), tomorrow.AddDate(0, 0, 1)); err == nil {
+		t.Error("expected the failed expiry to be reported")
+	}
+	if expired, _ := repo.GetByID(queued.ID); expired.Status != domain.TradeStatusExpired {
+		t.Errorf("expected the DAY order expired anyway, got %+v", expired)
+	}
+}
@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"simpletrading/tradeservice/internal/domain"
+	"time"
+)
+
+// SelfMatchReason is the order event reason of orders cancelled because
+// they would have matched an order of the same user.
+const SelfMatchReason = "cancelled to prevent self-match"
+
+// fill is one side of an execution, as seen by the user on that side.
+type fill struct {
+	execution    *domain.Execution
+	side         string
+	counterparty string
+	remaining    float64 // Quantity not yet paired into a round trip
+}
+
+// SurveillanceReport flags the suspicious trading between from and to: round
+// trips, where a user buys and sells at the same price within
+// RoundTripWindow, and the orders cancelled to prevent self-matches.
+func (uc *TradeUsecase) SurveillanceReport(from, to time.Time) (*domain.SurveillanceReport, error) {
+	executions, err := uc.repo.ListExecutionsBetween(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %v", err)
+	}
+	selfMatches, err := uc.repo.CountSelfMatches(SelfMatchReason, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count self-matches: %v", err)
+	}
+
+	return &domain.SurveillanceReport{
+		From:        from,
+		To:          to,
+		RoundTrips:  findRoundTrips(executions, uc.cfg.RoundTripWindow),
+		SelfMatches: selfMatches,
+		GeneratedAt: time.Now(),
+	}, nil
+}
+
+// findRoundTrips pairs every fill with the user's earliest unpaired fill on
+// the other side of the same symbol at the same price within the window.
+// Executions must be passed oldest first.
+func findRoundTrips(executions []domain.Execution, window time.Duration) []domain.RoundTrip {
+	trips := []domain.RoundTrip{}
+	recent := make(map[string][]*fill) // By user and symbol
+	for i := range executions {
+		e := &executions[i]
+		for _, f := range []*fill{
+			{execution: e, side: domain.SideBuy, counterparty: e.SellUserID, remaining: e.Quantity},
+			{execution: e, side: domain.SideSell, counterparty: e.BuyUserID, remaining: e.Quantity},
+		} {
+			user := e.BuyUserID
+			if f.side == domain.SideSell {
+				user = e.SellUserID
+			}
+			key := user + "|" + e.Symbol
+
+			// Forget the fills that are too old to pair
+			kept := recent[key][:0]
+			for _, earlier := range recent[key] {
+				if e.CreatedAt.Sub(earlier.execution.CreatedAt) <= window {
+					kept = append(kept, earlier)
+				}
+			}
+
+			for _, earlier := range kept {
+				if f.remaining <= 0 {
+					break
+				}
+				if earlier.side == f.side || earlier.remaining <= 0 || math.Abs(earlier.execution.Price-e.Price) > 1e-9 {
+					continue
+				}
+				qty := min(earlier.remaining, f.remaining)
+				earlier.remaining -= qty
+				f.remaining -= qty
+				trips = append(trips, domain.RoundTrip{
+					UserID:           user,
+					Symbol:           e.Symbol,
+					Price:            e.Price,
+					Quantity:         qty,
+					OpenExecutionID:  earlier.execution.ID,
+					CloseExecutionID: e.ID,
+					OpenedAt:         earlier.execution.CreatedAt,
+					ClosedAt:         e.CreatedAt,
+					SameCounterparty: earlier.counterparty == f.counterparty,
+				})
+			}
+			recent[key] = append(kept, f)
+		}
+	}
+	return trips
+}
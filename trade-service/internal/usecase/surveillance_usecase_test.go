@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestSurveillanceFlagsRoundTrips(t *testing.T) {
+	cfg := setupTestServices(t, 100)
+	cfg.RoundTripWindow = time.Minute
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, cfg)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+
+	// Alice's buy would match her own resting sell, so it is cancelled
+	uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideSell, 1, 105))
+	buy, _, err := uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 1, 105))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buy.Status != domain.TradeStatusCancelled || buy.FilledQuantity != 0 {
+		t.Errorf("expected the self-match to cancel the buy, got %+v", buy)
+	}
+
+	// Bob buys from carol and sells back to her at the same price
+	uc.PlaceTrade(ctx, "carol@example.com", limitOrder(domain.SideSell, 2, 100))
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideBuy, 2, 100))
+	uc.PlaceTrade(ctx, "carol@example.com", limitOrder(domain.SideBuy, 1, 100))
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 1, 100))
+
+	report, err := uc.SurveillanceReport(start, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.SelfMatches) != 1 || report.SelfMatches[0].UserID != "alice@example.com" || report.SelfMatches[0].Cancelled != 1 {
+		t.Errorf("expected one self-match of alice, got %+v", report.SelfMatches)
+	}
+	// Carol's side is a round trip too
+	if len(report.RoundTrips) != 2 {
+		t.Fatalf("expected bob's and carol's round trips, got %+v", report.RoundTrips)
+	}
+	for _, trip := range report.RoundTrips {
+		if trip.Quantity != 1 || trip.Price != 100 || !trip.SameCounterparty {
+			t.Errorf("unexpected round trip %+v", trip)
+		}
+	}
+
+	if trips := findRoundTrips([]domain.Execution{
+		{ID: 1, Symbol: "BTCUSD", BuyUserID: "bob", SellUserID: "carol", Price: 100, Quantity: 1, CreatedAt: start},
+		{ID: 2, Symbol: "BTCUSD", BuyUserID: "carol", SellUserID: "bob", Price: 100, Quantity: 1, CreatedAt: start.Add(2 * time.Minute)},
+	}, time.Minute); len(trips) != 0 {
+		t.Errorf("expected no round trips outside the window, got %+v", trips)
+	}
+}
@@ -1,86 +1,470 @@
 package usecase
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"simpletrading/tradeservice/internal/calendar"
 	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/matching"
 	"simpletrading/tradeservice/internal/repository/memory"
+	"simpletrading/tradeservice/internal/risk"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
 )
 
-type TradeUsecase struct {
-	repo *memory.TradeRepository
-	cfg  *config.Config
+const (
+	defaultTradePageSize = 20
+	maxTradePageSize     = 100
+
+	// feeVolumeWindow is the period of traded volume that sets the fee tier
+	feeVolumeWindow = 30 * 24 * time.Hour
+)
+
+var (
+	ErrTradeNotFound     = errors.New("trade not found")
+	ErrInvalidTrade      = errors.New("invalid trade")
+	ErrTradeRejected     = errors.New("trade rejected")
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	// ErrMarketDataUnavailable means the reference prices could not be
+	// fetched, so no order can be checked.
+	ErrMarketDataUnavailable = errors.New("market data unavailable")
+	// ErrMarketClosed means the order cannot wait for the symbol's next
+	// session.
+	ErrMarketClosed = errors.New("market closed")
+)
+
+// RejectionError lists the risk rules an order broke. It wraps
+// ErrTradeRejected.
+type RejectionError struct {
+	Violations []risk.Violation
+}
+
+func (e *RejectionError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return fmt.Sprintf("%v: %s", ErrTradeRejected, strings.Join(messages, "; "))
+}
+
+func (e *RejectionError) Unwrap() error {
+	return ErrTradeRejected
+}
+
+// RuleIDs returns the IDs of the broken rules.
+func (e *RejectionError) RuleIDs() []string {
+	ids := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		ids[i] = v.RuleID
+	}
+	return ids
 }
 
-func NewTradeUsecase(repo *memory.TradeRepository, cfg *config.Config) *TradeUsecase {
-	return &TradeUsecase{repo: repo, cfg: cfg}
+// PriceSource provides the reference prices of the last 24 hours. The second
+// result reports whether the prices are a stale fallback. PriceActivity
+// reports how much the price moved by time of day (UTC) over the last days,
+// in buckets of the given width.
+type PriceSource interface {
+	PriceStats(ctx context.Context) (domain.PriceStats, bool, error)
+	PriceActivity(ctx context.Context, days int, bucket time.Duration) (map[int]float64, error)
 }
 
-type TokenResponse struct {
-	AccessToken string `json:"access_token"`
+type TradeUsecase struct {
+	repo     *memory.TradeRepository
+	engine   *matching.Engine
+	rules    *risk.Engine
+	fees     *fees.Engine
+	calendar *calendar.Engine
+	halts    *HaltUsecase
+	prices   PriceSource
+	cfg      *config.Config
+	paper    bool // Trades go to the simulated paper book and ledger
+
+	// funds serializes the buying power checks with the orders and
+	// withdrawals that spend the checked cash
+	funds sync.Mutex
+	// algos serializes sending algo order slices with cancelling the orders.
+	// It is taken before funds.
+	algos sync.Mutex
 }
 
-func (uc *TradeUsecase) PlaceTrade(amount float64) error {
-	// Step 1: Get machine token from Auth Service
-	token, err := GetMachineToken(uc.cfg.AuthUrl, uc.cfg.ClientId, uc.cfg.ClientSecret)
+func NewTradeUsecase(repo *memory.TradeRepository, engine *matching.Engine, rules *risk.Engine, fees *fees.Engine, cal *calendar.Engine, halts *HaltUsecase, prices PriceSource, cfg *config.Config) *TradeUsecase {
+	return &TradeUsecase{repo: repo, engine: engine, rules: rules, fees: fees, calendar: cal, halts: halts, prices: prices, cfg: cfg}
+}
+
+// PlaceTrade validates the order, runs the risk rules against the prices of
+// the last 24 hours, matches it against the order book and stores the trade
+// for the given user together with the executions it produced. Orders that
+// fail the risk rules are stored as rejected; orders placed outside the
+// symbol's trading session are queued or refused, see scheduleTrade.
+func (uc *TradeUsecase) PlaceTrade(ctx context.Context, userID string, trade domain.Trade) (*domain.Trade, []domain.Execution, error) {
+	if err := ValidateTrade(&trade); err != nil {
+		return nil, nil, err
+	}
+
+	market, err := uc.marketPrices(ctx)
 	if err != nil {
-		return fmt.Errorf("auth failed: %v", err)
+		return nil, nil, err
 	}
 
-	// Step 2: Request the lowest data from Data Service with Authorization
-	req, err := http.NewRequest("GET", uc.cfg.DataUrl, nil)
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	return uc.placeTrade(userID, trade, market)
+}
+
+// placeTrade checks, matches and stores a validated order. Callers hold
+// uc.funds.
+func (uc *TradeUsecase) placeTrade(userID string, trade domain.Trade, market domain.PriceStats) (*domain.Trade, []domain.Execution, error) {
+	trade.UserID = userID
+	trade.ReferencePrice = market.Lowest
+	trade.Status = domain.TradeStatusNew
+	if trade.Type == domain.OrderTypeTrailingStop {
+		trade.Trail(market.Mark())
+	}
+	if err := uc.halts.Check(userID, trade.Symbol); err != nil {
+		return nil, nil, err
+	}
+	if err := uc.scheduleTrade(&trade, time.Now()); err != nil {
+		return nil, nil, err
+	}
+	created := domain.NewOrderEvent(&trade, "", "received")
+
+	// Step 4: Run the risk rules
+	if err := uc.checkRisk(&trade, market); err != nil {
+		var rejection *RejectionError
+		if !errors.As(err, &rejection) {
+			return nil, nil, err
+		}
+
+		trade.Status = domain.TradeStatusRejected
+		rejected := domain.NewOrderEvent(&trade, domain.TradeStatusNew, "rejected by "+strings.Join(rejection.RuleIDs(), ", "))
+		if err := uc.repo.Insert(&trade, created, rejected); err != nil {
+			return nil, nil, fmt.Errorf("failed to save trade: %v", err)
+		}
+		return nil, nil, rejection
+	}
+
+	trade.Status = domain.TradeStatusAccepted
+	accepted := domain.NewOrderEvent(&trade, domain.TradeStatusNew, "accepted")
+
+	// Conditional orders wait for their trigger and never enter the book
+	// directly; see RunTriggers
+	if domain.IsConditional(trade.Type) {
+		if err := uc.repo.Insert(&trade, created, accepted); err != nil {
+			return nil, nil, fmt.Errorf("failed to save trade: %v", err)
+		}
+		fmt.Printf("Trade accepted: %s %.4f %s %s %.2f\n", trade.Side, trade.Quantity, trade.Symbol, trade.Type, trade.StopPrice)
+		return &trade, nil, nil
+	}
+
+	// Orders placed while the market is closed wait for the open; see
+	// RunSessions
+	if trade.ActivatesAt != nil {
+		if err := uc.repo.Insert(&trade, created, accepted); err != nil {
+			return nil, nil, fmt.Errorf("failed to save trade: %v", err)
+		}
+		fmt.Printf("Trade queued: %s %.4f %s @ %.2f until %s\n", trade.Side, trade.Quantity, trade.Symbol, trade.Price, trade.ActivatesAt.Format(time.RFC3339))
+		return &trade, nil, nil
+	}
+
+	// Step 5: Match against the order book and save the trade with its fills
+	match, err := uc.engine.Submit(&trade, func(result *matching.Result) error {
+		if err := uc.chargeFees(&trade, result); err != nil {
+			return err
+		}
+		events := append([]domain.OrderEvent{created, accepted}, matchEvents(&trade, domain.TradeStatusAccepted, result)...)
+		return uc.saveMatch(&trade, result, events)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return nil, nil, fmt.Errorf("failed to save trade: %v", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err := http.DefaultClient.Do(req)
+	fmt.Printf("Trade accepted: %s %.4f %s @ %.2f, filled %.4f\n", trade.Side, trade.Quantity, trade.Symbol, trade.Price, trade.FilledQuantity)
+
+	return &trade, match.Executions, nil
+}
+
+// saveMatch stores a match. The linked orders the repository cancels
+// because of its fills are handed back to the engine to take off the book.
+func (uc *TradeUsecase) saveMatch(trade *domain.Trade, result *matching.Result, events []domain.OrderEvent) error {
+	cancelled, err := uc.repo.SaveMatch(trade, result.Makers, result.Executions, events)
 	if err != nil {
-		return fmt.Errorf("data request failed: %v", err)
+		return err
 	}
-	defer resp.Body.Close()
+	result.Cancelled = cancelled
+	return nil
+}
 
-	// Check if the response status is OK
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// marketPrices fetches the lowest, highest and last price of the last 24
+// hours from the Data Service.
+func (uc *TradeUsecase) marketPrices(ctx context.Context) (domain.PriceStats, error) {
+	stats, stale, err := uc.prices.PriceStats(ctx)
+	if err != nil {
+		return domain.PriceStats{}, fmt.Errorf("%w: %v", ErrMarketDataUnavailable, err)
+	}
+	if stale {
+		log.Println("Checking trade against last known prices")
 	}
+	return stats, nil
+}
+
+// checkRisk runs the risk rules and, for buys, the buying power check
+// against the order. Callers hold uc.funds. Broken rules are reported
+// as a *RejectionError; any other error means the check could not run.
+func (uc *TradeUsecase) checkRisk(trade *domain.Trade, market domain.PriceStats) error {
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 
-	// Read and log the response body for debugging
-	respBody, err := io.ReadAll(resp.Body)
+	daily, err := uc.repo.TradedNotional(trade.UserID, midnight)
+	if err != nil {
+		return fmt.Errorf("failed to load daily notional: %v", err)
+	}
+	position, err := uc.repo.NetPosition(trade.UserID, trade.Symbol)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
+		return fmt.Errorf("failed to load position: %v", err)
+	}
+
+	violations := uc.rules.Evaluate(trade, &risk.Context{
+		Market:        market,
+		DailyNotional: daily,
+		Position:      position,
+	})
+	if uc.cfg.BuyingPowerCheck && trade.Side == domain.SideBuy {
+		v, err := uc.checkBuyingPower(trade, market)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	if len(violations) > 0 {
+		return &RejectionError{Violations: violations}
+	}
+	return nil
+}
+
+// chargeFees prices the fills of a match: the incoming order pays the taker
+// rate and the resting orders the maker rate, each for the 30-day volume of
+// its user. The fees are set on the executions and added to the orders; an
+// order's first charged fill also pays the flat fee.
+func (uc *TradeUsecase) chargeFees(trade *domain.Trade, result *matching.Result) error {
+	since := time.Now().Add(-feeVolumeWindow)
+	volumes := make(map[string]float64)
+	charge := func(order *domain.Trade, notional float64, maker bool) (float64, error) {
+		volume, ok := volumes[order.UserID]
+		if !ok {
+			var err error
+			if volume, err = uc.repo.TradedNotional(order.UserID, since); err != nil {
+				return 0, fmt.Errorf("failed to load 30-day volume: %v", err)
+			}
+			volumes[order.UserID] = volume
+		}
+		fee := uc.fees.Fee(notional, volume, maker, order.Fee == 0)
+		order.Fee += fee
+		return fee, nil
+	}
+
+	makers := make(map[uint]*domain.Trade, len(result.Makers))
+	for i := range result.Makers {
+		makers[result.Makers[i].ID] = &result.Makers[i]
 	}
 
-	// Log the raw response body to see what we are getting
-	fmt.Printf("Raw response body: %s\n", respBody)
-	// Step 3: Unmarshal the response body into a map with the "lowest" key
-	var result map[string]float64
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return fmt.Errorf("data decode failed: %v", err)
+	for i := range result.Executions {
+		e := &result.Executions[i]
+		makerID := e.SellTradeID
+		if trade.Side == domain.SideSell {
+			makerID = e.BuyTradeID
+		}
+
+		notional := e.Price * e.Quantity
+		takerFee, err := charge(trade, notional, false)
+		if err != nil {
+			return err
+		}
+		makerFee, err := charge(makers[makerID], notional, true)
+		if err != nil {
+			return err
+		}
+
+		e.BuyFee, e.SellFee = takerFee, makerFee
+		if trade.Side == domain.SideSell {
+			e.BuyFee, e.SellFee = makerFee, takerFee
+		}
 	}
+	return nil
+}
 
-	// Extract the lowest value from the map
-	lowest, ok := result["lowest"]
-	if !ok {
-		return fmt.Errorf("missing 'lowest' value in the response")
+// matchEvents describes the status changes a match caused: one for the
+// incoming order if its status moved on, and one per maker it filled or
+// cancelled to prevent a self-match.
+func matchEvents(trade *domain.Trade, from string, result *matching.Result) []domain.OrderEvent {
+	var events []domain.OrderEvent
+	if trade.Status != from {
+		reason := "matched"
+		switch trade.Status {
+		case domain.TradeStatusExpired:
+			reason = "unfilled remainder expired"
+		case domain.TradeStatusCancelled:
+			reason = SelfMatchReason
+		}
+		events = append(events, domain.NewOrderEvent(trade, from, reason))
+	}
+	for i := range result.Makers {
+		reason := "matched"
+		if result.Makers[i].Status == domain.TradeStatusCancelled {
+			reason = SelfMatchReason
+		}
+		events = append(events, domain.NewOrderEvent(&result.Makers[i], result.MakerStatuses[i], reason))
 	}
+	return events
+}
 
-	// Step 4: Validate the trade price (assuming 'min' is predefined)
-	if amount < lowest/2 {
-		return fmt.Errorf("trade price too low; must be at least %.2f", lowest/2)
+// ValidateTrade checks the order fields and normalizes symbol, side, type and
+// time in force. Errors wrap ErrInvalidTrade.
+func ValidateTrade(trade *domain.Trade) error {
+	trade.Symbol = strings.ToUpper(strings.TrimSpace(trade.Symbol))
+	trade.Side = strings.ToLower(trade.Side)
+	trade.Type = strings.ToLower(trade.Type)
+	trade.TimeInForce = strings.ToUpper(trade.TimeInForce)
+	if trade.TimeInForce == "" {
+		trade.TimeInForce = domain.TimeInForceGTC
 	}
 
-	fmt.Printf("Trade accepted: %.2f\n", amount)
+	if trade.Symbol == "" {
+		return fmt.Errorf("%w: symbol is required", ErrInvalidTrade)
+	}
+	if trade.Side != domain.SideBuy && trade.Side != domain.SideSell {
+		return fmt.Errorf("%w: side must be buy or sell", ErrInvalidTrade)
+	}
+	if trade.Quantity <= 0 {
+		return fmt.Errorf("%w: quantity must be positive", ErrInvalidTrade)
+	}
+
+	switch trade.TimeInForce {
+	case domain.TimeInForceGTC, domain.TimeInForceIOC, domain.TimeInForceFOK, domain.TimeInForceDAY:
+	default:
+		return fmt.Errorf("%w: time_in_force must be GTC, IOC, FOK or DAY", ErrInvalidTrade)
+	}
 
-	// TODO: Step 5: Save trade in database
-	// return uc.repo.Insert(&Trade{Amount: amount, Timestamp: time.Now()})
+	if trade.Type != domain.OrderTypeTrailingStop && (trade.TrailAmount != 0 || trade.TrailPercent != 0) {
+		return fmt.Errorf("%w: only trailing_stop orders take a trail", ErrInvalidTrade)
+	}
+
+	switch trade.Type {
+	case domain.OrderTypeMarket:
+		if trade.Price != 0 || trade.StopPrice != 0 {
+			return fmt.Errorf("%w: market orders take no price", ErrInvalidTrade)
+		}
+	case domain.OrderTypeLimit:
+		if trade.Price <= 0 {
+			return fmt.Errorf("%w: limit orders need a positive price", ErrInvalidTrade)
+		}
+		if trade.StopPrice != 0 {
+			return fmt.Errorf("%w: limit orders take no stop_price", ErrInvalidTrade)
+		}
+	case domain.OrderTypeStop:
+		if trade.StopPrice <= 0 {
+			return fmt.Errorf("%w: stop orders need a positive stop_price", ErrInvalidTrade)
+		}
+		if trade.Price != 0 {
+			return fmt.Errorf("%w: stop orders take no price", ErrInvalidTrade)
+		}
+	case domain.OrderTypeStopLimit:
+		if trade.StopPrice <= 0 || trade.Price <= 0 {
+			return fmt.Errorf("%w: stop_limit orders need a positive stop_price and price", ErrInvalidTrade)
+		}
+	case domain.OrderTypeTakeProfit:
+		if trade.StopPrice <= 0 {
+			return fmt.Errorf("%w: take_profit orders need a positive stop_price", ErrInvalidTrade)
+		}
+		if trade.Price < 0 {
+			return fmt.Errorf("%w: price must not be negative", ErrInvalidTrade)
+		}
+	case domain.OrderTypeTrailingStop:
+		if (trade.TrailAmount > 0) == (trade.TrailPercent > 0) || trade.TrailAmount < 0 || trade.TrailPercent < 0 {
+			return fmt.Errorf("%w: trailing_stop orders need either a positive trail_amount or trail_percent", ErrInvalidTrade)
+		}
+		if trade.TrailPercent >= 100 {
+			return fmt.Errorf("%w: trail_percent must be below 100", ErrInvalidTrade)
+		}
+		if trade.Price != 0 || trade.StopPrice != 0 {
+			return fmt.Errorf("%w: trailing_stop orders take no price or stop_price", ErrInvalidTrade)
+		}
+	default:
+		return fmt.Errorf("%w: type must be market, limit, stop, stop_limit, take_profit or trailing_stop", ErrInvalidTrade)
+	}
 
 	return nil
 }
 
+// ListTrades returns a page of the user's trades, newest first, along with the
+// cursor for the next page (0 when there are no more trades).
+func (uc *TradeUsecase) ListTrades(userID string, filter domain.TradeFilter) ([]domain.Trade, uint, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultTradePageSize
+	case filter.Limit > maxTradePageSize:
+		filter.Limit = maxTradePageSize
+	}
+	filter.UserID = userID
+
+	// Fetch one extra trade to find out whether another page exists
+	limit := filter.Limit
+	filter.Limit++
+	trades, err := uc.repo.List(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list trades: %v", err)
+	}
+
+	var next uint
+	if len(trades) > limit {
+		trades = trades[:limit]
+		next = trades[limit-1].ID
+	}
+
+	return trades, next, nil
+}
+
+// ListExecutions returns the fills of a trade owned by the user.
+func (uc *TradeUsecase) ListExecutions(userID string, tradeID uint) ([]domain.Execution, error) {
+	if _, err := uc.GetTrade(userID, tradeID); err != nil {
+		return nil, err
+	}
+
+	executions, err := uc.repo.ListExecutions(tradeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %v", err)
+	}
+	return executions, nil
+}
+
+// GetTrade returns a single trade owned by the user.
+func (uc *TradeUsecase) GetTrade(userID string, id uint) (*domain.Trade, error) {
+	trade, err := uc.repo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTradeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trade: %v", err)
+	}
+
+	// Other users' trades are reported as missing rather than forbidden
+	if trade.UserID != userID {
+		return nil, ErrTradeNotFound
+	}
+
+	return trade, nil
+}
+
 // func (uc *TradeUsecase) PlaceTrade(trade domain.Trade) error {
 // 	// Step 1: Get machine token
 // 	token, err := GetMachineToken(uc.cfg.AuthUrl, uc.cfg.ClientId, uc.cfg.ClientSecret)
@@ -127,35 +511,6 @@ func (uc *TradeUsecase) PlaceTrade(amount float64) error {
 // 	return uc.repo.Insert(&trade)
 // }
 
-func GetMachineToken(authURL, clientID, clientSecret string) (string, error) {
-	req, err := http.NewRequest("GET", authURL, nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.SetBasicAuth(clientID, clientSecret)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get token: %s", resp.Status)
-	}
-
-	var tokenRes TokenResponse
-	err = json.NewDecoder(resp.Body).Decode(&tokenRes)
-	if err != nil {
-		return "", err
-	}
-
-	log.Printf("Received token: %s", tokenRes.AccessToken)
-
-	return tokenRes.AccessToken, nil
-}
-
 // func (uc *TradeUsecase) PlaceTrade(userID string, price float64) error {
 // 	lowest, err := uc.repo.GetLowestPriceInLast24Hours()
 // 	if err == nil && price < (lowest/2.0) {
@@ -0,0 +1,465 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"simpletrading/tradeservice/internal/auth"
+	"simpletrading/tradeservice/internal/calendar"
+	"simpletrading/tradeservice/internal/config"
+	"simpletrading/tradeservice/internal/dataclient"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/fees"
+	"simpletrading/tradeservice/internal/matching"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"simpletrading/tradeservice/internal/risk"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// setupTestDB creates an empty database with the schema of the service.
+func setupTestDB(t *testing.T) *gorm.DB {
+	return config.InitDatabase(filepath.Join(t.TempDir(), "trade.db"))
+}
+
+// setupTestServices starts fake auth and data services; the data service
+// reports the given lowest price.
+func setupTestServices(t *testing.T, lowest float64) *config.Config {
+	return setupTestServicesWithActivity(t, lowest, nil)
+}
+
+// setupTestServicesWithActivity also has the data service report the given
+// price activity by time of day.
+func setupTestServicesWithActivity(t *testing.T, lowest float64, activity map[int]float64) *config.Config {
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "test-token"})
+	}))
+	t.Cleanup(auth.Close)
+
+	data := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/data/activity" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"bucket": r.URL.Query().Get("bucket"), "activity": activity})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]float64{"lowest": lowest})
+	}))
+	t.Cleanup(data.Close)
+
+	return &config.Config{AuthUrl: auth.URL, DataUrl: data.URL, DataActivityUrl: data.URL + "/data/activity"}
+}
+
+// newTestUsecase wires a usecase with an empty order book and the default
+// risk rules.
+func newTestUsecase(t *testing.T, repo *memory.TradeRepository, cfg *config.Config) *TradeUsecase {
+	rules, err := risk.NewEngine("")
+	if err != nil {
+		t.Fatalf("failed to load risk rules: %v", err)
+	}
+	tokens := auth.NewTokenSource(cfg.AuthUrl, cfg.ClientId, cfg.ClientSecret)
+	prices := dataclient.New(cfg.DataUrl, tokens, dataclient.Options{ActivityURL: cfg.DataActivityUrl})
+	feeEngine, err := fees.NewEngine("")
+	if err != nil {
+		t.Fatalf("failed to load fee schedule: %v", err)
+	}
+	sessions, err := calendar.NewEngine("")
+	if err != nil {
+		t.Fatalf("failed to load trading calendar: %v", err)
+	}
+	halts := NewHaltUsecase(memory.NewHaltRepository(setupTestDB(t)), cfg)
+	return NewTradeUsecase(repo, matching.NewEngine(), rules, feeEngine, sessions, halts, prices, cfg)
+}
+
+func limitOrder(side string, quantity, price float64) domain.Trade {
+	return domain.Trade{
+		Symbol:   "btcusd",
+		Side:     side,
+		Type:     domain.OrderTypeLimit,
+		Quantity: quantity,
+		Price:    price,
+	}
+}
+
+func TestPlaceTradePersistsAcceptedTrade(t *testing.T) {
+	db := setupTestDB(t)
+	uc := newTestUsecase(t, memory.NewTradeRepository(db), setupTestServices(t, 100))
+
+	trade, _, err := uc.PlaceTrade(context.Background(), "alice@example.com", limitOrder(domain.SideBuy, 2, 60))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var stored domain.Trade
+	if err := db.First(&stored, trade.ID).Error; err != nil {
+		t.Fatalf("trade not stored: %v", err)
+	}
+	if stored.UserID != "alice@example.com" || stored.Symbol != "BTCUSD" || stored.Price != 60 || stored.ReferencePrice != 100 {
+		t.Errorf("unexpected stored trade: %+v", stored)
+	}
+	if stored.Status != domain.TradeStatusAccepted {
+		t.Errorf("expected status %q, got %q", domain.TradeStatusAccepted, stored.Status)
+	}
+}
+
+func TestPlaceTradeMatchesRestingOrder(t *testing.T) {
+	db := setupTestDB(t)
+	uc := newTestUsecase(t, memory.NewTradeRepository(db), setupTestServices(t, 100))
+
+	sell, _, err := uc.PlaceTrade(context.Background(), "bob@example.com", limitOrder(domain.SideSell, 5, 90))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	buy, executions, err := uc.PlaceTrade(context.Background(), "alice@example.com", limitOrder(domain.SideBuy, 2, 95))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buy.Status != domain.TradeStatusFilled || len(executions) != 1 {
+		t.Fatalf("expected buy to fill in one execution, got %+v %+v", buy, executions)
+	}
+
+	var stored domain.Execution
+	if err := db.First(&stored).Error; err != nil {
+		t.Fatalf("execution not stored: %v", err)
+	}
+	if stored.BuyTradeID != buy.ID || stored.SellTradeID != sell.ID || stored.Price != 90 || stored.Quantity != 2 {
+		t.Errorf("unexpected stored execution: %+v", stored)
+	}
+
+	var maker domain.Trade
+	db.First(&maker, sell.ID)
+	if maker.Status != domain.TradeStatusPartiallyFilled || maker.FilledQuantity != 2 {
+		t.Errorf("unexpected maker state: %+v", maker)
+	}
+}
+
+func TestPortfolioTracksFills(t *testing.T) {
+	uc := newTestUsecase(t, memory.NewTradeRepository(setupTestDB(t)), setupTestServices(t, 100))
+	ctx := context.Background()
+
+	// Alice buys 4 @ 90 from Bob, then sells 1 back to him @ 110
+	orders := []struct {
+		user  string
+		order domain.Trade
+	}{
+		{"bob@example.com", limitOrder(domain.SideSell, 4, 90)},
+		{"alice@example.com", limitOrder(domain.SideBuy, 2, 95)},
+		{"alice@example.com", limitOrder(domain.SideBuy, 2, 100)},
+		{"alice@example.com", limitOrder(domain.SideSell, 1, 110)},
+		{"bob@example.com", limitOrder(domain.SideBuy, 1, 110)},
+	}
+	for _, o := range orders {
+		if _, _, err := uc.PlaceTrade(ctx, o.user, o.order); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	alice, err := uc.GetPortfolio(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alice.Positions) != 1 {
+		t.Fatalf("expected one position, got %+v", alice.Positions)
+	}
+	p := alice.Positions[0]
+	if p.Quantity != 3 || p.AverageCost != 90 || p.RealizedPnL != 20 {
+		t.Errorf("unexpected position: %+v", p.Position)
+	}
+	// Marked at the 24h lowest, as the fake data service reports no last price
+	if p.MarkPrice == nil || *p.MarkPrice != 100 || *p.UnrealizedPnL != 30 || *alice.UnrealizedPnL != 30 {
+		t.Errorf("unexpected valuation: %+v", p)
+	}
+
+	bob, err := uc.GetPosition(ctx, "bob@example.com", "btcusd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bob.Quantity != -3 || bob.AverageCost != 90 || bob.RealizedPnL != -20 {
+		t.Errorf("unexpected position: %+v", bob.Position)
+	}
+}
+
+func TestBuyingPowerAndSettlement(t *testing.T) {
+	cfg := setupTestServices(t, 100)
+	cfg.BuyingPowerCheck = true
+	uc := newTestUsecase(t, memory.NewTradeRepository(setupTestDB(t)), cfg)
+	ctx := context.Background()
+
+	if _, err := uc.Deposit("alice@example.com", "usd", 500, "wire-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first buy reserves 400 of the 500, leaving too little for the second
+	if _, _, err := uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 2, 200)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _, err := uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 1, 150))
+	var rejection *RejectionError
+	if !errors.As(err, &rejection) || rejection.RuleIDs()[0] != BuyingPowerRuleID {
+		t.Fatalf("expected a buying power rejection, got %v", err)
+	}
+	if _, err := uc.Withdraw("alice@example.com", "USD", 200, ""); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	if _, _, err := uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 2, 200)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	alice, _ := uc.GetBalances("alice@example.com")
+	bob, _ := uc.GetBalances("bob@example.com")
+	if len(alice) != 1 || alice[0] != (domain.Balance{Currency: "USD", Cash: 100, Available: 100}) {
+		t.Errorf("unexpected balances for alice: %+v", alice)
+	}
+	if len(bob) != 1 || bob[0] != (domain.Balance{Currency: "USD", Cash: 400, Available: 400}) {
+		t.Errorf("unexpected balances for bob: %+v", bob)
+	}
+
+	check, err := uc.CheckLedger()
+	if err != nil || !check.Consistent || check.Totals["USD"] != 0 || check.Entries != 2 {
+		t.Errorf("expected a consistent ledger, got %+v, %v", check, err)
+	}
+}
+
+func TestPlaceTradeChargesFees(t *testing.T) {
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, setupTestServices(t, 100))
+	schedule := fees.Schedule{Flat: 1, Tiers: []fees.Tier{
+		{MinVolume: 1000, MakerPercent: 0, TakerPercent: 0.1},
+		{MinVolume: 0, MakerPercent: 0.1, TakerPercent: 0.2},
+	}}
+	var err error
+	if uc.fees, err = fees.NewEngineWithSchedule(schedule); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	sell, _, _ := uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideSell, 4, 100))
+
+	// 0.2% taker plus the flat fee; the maker pays 0.1% plus the flat fee
+	buy, executions, err := uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 2, 100))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buy.Fee != 1.4 || executions[0].BuyFee != 1.4 || executions[0].SellFee != 1.2 {
+		t.Errorf("unexpected fees: trade %.4f, execution %+v", buy.Fee, executions[0])
+	}
+
+	// The maker's second fill pays no flat fee again
+	uc.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 2, 100))
+
+	maker, _ := repo.GetByID(sell.ID)
+	if diff := maker.Fee - 1.4; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("expected the maker to pay 1.4, got %.4f", maker.Fee)
+	}
+	collected, _ := repo.CashBalances(domain.AccountFees)
+	if diff := collected["USD"] - 4.2; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("expected 4.2 USD of fees in the ledger, got %+v", collected)
+	}
+
+	// Volume of 1000 or more moves to the cheaper tier
+	if rate := schedule.Rate(1500, false); rate != 0.1 {
+		t.Errorf("expected the second tier's taker rate, got %.2f", rate)
+	}
+}
+
+func TestPaperTradingIsIsolated(t *testing.T) {
+	cfg := setupTestServices(t, 100)
+	cfg.BuyingPowerCheck = true
+	cfg.PaperStartingCash = 1000
+	liveDB := setupTestDB(t)
+	live := newTestUsecase(t, memory.NewTradeRepository(liveDB), cfg)
+	paperRepo := memory.NewTradeRepository(setupTestDB(t))
+	paper := NewPaperTradeUsecase(paperRepo, matching.NewEngine(), live.rules, live.fees, live.calendar, live.halts, live.prices, cfg)
+	ctx := context.Background()
+
+	if err := live.SetTradingMode(paper, "alice@example.com", "PAPER"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mode, _ := live.TradingMode("alice@example.com"); mode != domain.TradingModePaper {
+		t.Errorf("expected paper mode, got %q", mode)
+	}
+	if err := live.SetTradingMode(paper, "alice@example.com", "demo"); !errors.Is(err, ErrInvalidTradingMode) {
+		t.Errorf("expected ErrInvalidTradingMode, got %v", err)
+	}
+
+	// Funding happens once; the live account stays empty
+	paper.FundPaperAccount("alice@example.com")
+	paper.FundPaperAccount("alice@example.com")
+	if _, _, err := paper.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 2, 200)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	balances, _ := paper.GetBalances("alice@example.com")
+	if len(balances) != 1 || balances[0] != (domain.Balance{Currency: "USD", Cash: 1000, Reserved: 400, Available: 600}) {
+		t.Errorf("unexpected paper balances: %+v", balances)
+	}
+	var count int64
+	liveDB.Model(&domain.Trade{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no live trades, got %d", count)
+	}
+	if _, _, err := live.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 2, 200)); err == nil {
+		t.Error("expected the unfunded live account to be rejected")
+	}
+}
+
+func TestSetTradingModeRefusedWithOpenOrders(t *testing.T) {
+	cfg := setupTestServices(t, 100)
+	live := newTestUsecase(t, memory.NewTradeRepository(setupTestDB(t)), cfg)
+	paperRepo := memory.NewTradeRepository(setupTestDB(t))
+	paper := NewPaperTradeUsecase(paperRepo, matching.NewEngine(), live.rules, live.fees, live.calendar, live.halts, live.prices, cfg)
+	ctx := context.Background()
+
+	resting, _, err := live.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 2, 150))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := live.SetTradingMode(paper, "alice@example.com", "paper"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+	if mode, _ := live.TradingMode("alice@example.com"); mode != domain.TradingModeLive {
+		t.Errorf("expected the account to stay live, got %q", mode)
+	}
+
+	// The live order can still be cancelled, after which the switch goes through
+	if _, err := live.CancelOrder("alice@example.com", resting.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := live.SetTradingMode(paper, "alice@example.com", "paper"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The same holds for paper orders when switching back
+	if _, _, err := paper.PlaceTrade(ctx, "alice@example.com", limitOrder(domain.SideBuy, 2, 150)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := live.SetTradingMode(paper, "alice@example.com", "live"); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestPlaceTradeRejectsLowPrice(t *testing.T) {
+	db := setupTestDB(t)
+	uc := newTestUsecase(t, memory.NewTradeRepository(db), setupTestServices(t, 100))
+
+	_, _, err := uc.PlaceTrade(context.Background(), "alice@example.com", limitOrder(domain.SideBuy, 1, 40))
+	var rejection *RejectionError
+	if !errors.As(err, &rejection) {
+		t.Fatalf("expected trade below half the lowest price to be rejected, got %v", err)
+	}
+	if ids := rejection.RuleIDs(); len(ids) != 1 || ids[0] != "min-price-half-low" {
+		t.Errorf("unexpected rule ids: %v", ids)
+	}
+
+	var stored domain.Trade
+	if err := db.First(&stored).Error; err != nil {
+		t.Fatalf("rejected trade not stored: %v", err)
+	}
+	if stored.Status != domain.TradeStatusRejected {
+		t.Errorf("expected status %q, got %q", domain.TradeStatusRejected, stored.Status)
+	}
+}
+
+func TestListTradesPaginatesNewestFirst(t *testing.T) {
+	db := setupTestDB(t)
+	repo := memory.NewTradeRepository(db)
+	uc := newTestUsecase(t, repo, &config.Config{})
+
+	for _, price := range []float64{10, 20, 30} {
+		repo.Insert(&domain.Trade{UserID: "alice@example.com", Price: price, Status: domain.TradeStatusAccepted})
+	}
+	repo.Insert(&domain.Trade{UserID: "bob@example.com", Price: 40, Status: domain.TradeStatusAccepted})
+
+	page, next, err := uc.ListTrades("alice@example.com", domain.TradeFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page) != 2 || page[0].Price != 30 || page[1].Price != 20 {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	if next == 0 {
+		t.Fatal("expected a cursor for the next page")
+	}
+
+	page, next, err = uc.ListTrades("alice@example.com", domain.TradeFilter{Limit: 2, Cursor: next})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page) != 1 || page[0].Price != 10 || next != 0 {
+		t.Fatalf("unexpected last page: %+v (next %d)", page, next)
+	}
+}
+
+func TestListTradesCapsPageSize(t *testing.T) {
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, &config.Config{})
+
+	for i := 0; i < maxTradePageSize+5; i++ {
+		repo.Insert(&domain.Trade{UserID: "alice@example.com", Price: 10, Status: domain.TradeStatusAccepted})
+	}
+
+	if page, _, _ := uc.ListTrades("alice@example.com", domain.TradeFilter{}); len(page) != defaultTradePageSize {
+		t.Errorf("expected the default page of %d, got %d", defaultTradePageSize, len(page))
+	}
+	page, next, _ := uc.ListTrades("alice@example.com", domain.TradeFilter{Limit: 1000})
+	if len(page) != maxTradePageSize || next == 0 {
+		t.Errorf("expected a full page of %d and a cursor, got %d (next %d)", maxTradePageSize, len(page), next)
+	}
+}
+
+func TestGetTradeHidesOtherUsersTrades(t *testing.T) {
+	db := setupTestDB(t)
+	repo := memory.NewTradeRepository(db)
+	uc := newTestUsecase(t, repo, &config.Config{})
+
+	trade := &domain.Trade{UserID: "bob@example.com", Price: 40, Status: domain.TradeStatusAccepted}
+	repo.Insert(trade)
+
+	if _, err := uc.GetTrade("alice@example.com", trade.ID); err != ErrTradeNotFound {
+		t.Errorf("expected ErrTradeNotFound, got %v", err)
+	}
+}
+
+func TestValidateTrade(t *testing.T) {
+	tests := []struct {
+		name  string
+		trade domain.Trade
+		valid bool
+	}{
+		{"limit", limitOrder(domain.SideSell, 1, 10), true},
+		{"market", domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 1}, true},
+		{"stop", domain.Trade{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeStop, Quantity: 1, StopPrice: 9}, true},
+		{"missing symbol", domain.Trade{Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 1}, false},
+		{"bad side", domain.Trade{Symbol: "BTCUSD", Side: "hold", Type: domain.OrderTypeMarket, Quantity: 1}, false},
+		{"zero quantity", limitOrder(domain.SideBuy, 0, 10), false},
+		{"limit without price", limitOrder(domain.SideBuy, 1, 0), false},
+		{"market with price", domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 1, Price: 5}, false},
+		{"stop without stop price", domain.Trade{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeStop, Quantity: 1}, false},
+		{"take profit", domain.Trade{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeTakeProfit, Quantity: 1, StopPrice: 12, Price: 11}, true},
+		{"trailing stop", domain.Trade{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeTrailingStop, Quantity: 1, TrailPercent: 2}, true},
+		{"stop limit without price", domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeStopLimit, Quantity: 1, StopPrice: 9}, false},
+		{"trailing stop with two trails", domain.Trade{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeTrailingStop, Quantity: 1, TrailAmount: 1, TrailPercent: 2}, false},
+		{"bad time in force", domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeMarket, Quantity: 1, TimeInForce: "GTD"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trade := tt.trade
+			err := ValidateTrade(&trade)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidTrade) {
+				t.Errorf("expected ErrInvalidTrade, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/matching"
+	"time"
+)
+
+const triggerPollInterval = time.Second
+
+// errNotTriggered means a conditional order changed before it could be
+// released: it was cancelled, amended or released already.
+var errNotTriggered = errors.New("order no longer triggered")
+
+// RunTriggers releases the conditional orders whose trigger the price
+// reaches, and moves trailing stops along with the price, until ctx is done.
+// The orders are read from the database on every check, so they survive a
+// restart.
+func (uc *TradeUsecase) RunTriggers(ctx context.Context) {
+	ticker := time.NewTicker(triggerPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := uc.checkTriggers(ctx, now); err != nil {
+				log.Println("Failed to check order triggers:", err)
+			}
+		}
+	}
+}
+
+// checkTriggers checks the waiting conditional orders against the latest
+// price.
+func (uc *TradeUsecase) checkTriggers(ctx context.Context, now time.Time) error {
+	orders, err := uc.repo.ListConditional()
+	if err != nil || len(orders) == 0 {
+		return err
+	}
+	price, ok := uc.markPrice(ctx)
+	if !ok {
+		return nil
+	}
+	return uc.triggerOrders(orders, price, now)
+}
+
+// triggerOrders releases the orders the price triggers while their market
+// is open and they are not halted, and trails the trailing stops it does
+// not trigger. An order that fails does not hold up the others; the errors
+// are returned together.
+func (uc *TradeUsecase) triggerOrders(orders []domain.Trade, price float64, now time.Time) error {
+	halts, err := uc.halts.ListActive()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range orders {
+		order := &orders[i]
+		switch {
+		case order.StopPrice > 0 && order.Triggered(price) && uc.calendar.IsOpen(order.Symbol, now) && haltError(halts, order.UserID, order.Symbol) == nil:
+			if err := uc.release(order, price, now); err != nil {
+				errs = append(errs, err)
+			}
+		case order.Type == domain.OrderTypeTrailingStop && order.Trail(price):
+			if _, err := uc.repo.SaveStopPrice(order); err != nil {
+				errs = append(errs, fmt.Errorf("failed to trail stop of trade %d: %v", order.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// release sends a triggered conditional order to the book as a market or
+// limit order. Its risk and buying power were checked when it was placed,
+// so a protective order is not held back by them now.
+func (uc *TradeUsecase) release(order *domain.Trade, price float64, now time.Time) error {
+	uc.funds.Lock()
+	defer uc.funds.Unlock()
+
+	var trade *domain.Trade
+	var triggered domain.OrderEvent
+	_, err := uc.engine.Amend(order.Symbol, order.ID, func() (*domain.Trade, bool, error) {
+		// Re-read with the book locked so a cancel or amend that raced the
+		// check is seen
+		current, err := uc.repo.GetByID(order.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		if current.Status != domain.TradeStatusAccepted || !domain.IsConditional(current.Type) ||
+			current.StopPrice <= 0 || !current.Triggered(price) {
+			return nil, false, errNotTriggered
+		}
+
+		trade = current
+		trade.Release(now)
+		triggered = domain.NewOrderEvent(trade, domain.TradeStatusAccepted,
+			fmt.Sprintf("%s triggered at %.2f", trade.TriggeredFrom, price))
+		return trade, false, nil
+	}, func(result *matching.Result) error {
+		if err := uc.chargeFees(trade, result); err != nil {
+			return err
+		}
+		events := append([]domain.OrderEvent{triggered}, matchEvents(trade, domain.TradeStatusAccepted, result)...)
+		return uc.saveMatch(trade, result, events)
+	})
+	if errors.Is(err, errNotTriggered) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release trade %d: %v", order.ID, err)
+	}
+
+	fmt.Printf("Trade triggered: %d %s at %.2f, filled %.4f\n", trade.ID, trade.TriggeredFrom, price, trade.FilledQuantity)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"simpletrading/tradeservice/internal/domain"
+	"simpletrading/tradeservice/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestConditionalOrdersTrigger(t *testing.T) {
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, setupTestServices(t, 100))
+	ctx := context.Background()
+
+	uc.PlaceTrade(ctx, "bob@example.com", limitOrder(domain.SideBuy, 5, 90))
+	conditional := []domain.Trade{
+		{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeTrailingStop, Quantity: 1, TrailAmount: 5},
+		{Symbol: "BTCUSD", Side: domain.SideSell, Type: domain.OrderTypeTakeProfit, Quantity: 1, StopPrice: 120},
+		{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeStopLimit, Quantity: 1, StopPrice: 110, Price: 85},
+	}
+	ids := make([]uint, len(conditional))
+	for i, order := range conditional {
+		trade, _, err := uc.PlaceTrade(ctx, "alice@example.com", order)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids[i] = trade.ID
+	}
+	if trailing, _ := repo.GetByID(ids[0]); trailing.StopPrice != 95 {
+		t.Fatalf("expected the trailing stop 5 below the mark of 100, got %.2f", trailing.StopPrice)
+	}
+
+	// The rise triggers the buy stop and drags the trailing stop up to 105
+	for _, price := range []float64{110, 104} {
+		waiting, _ := repo.ListConditional()
+		if err := uc.triggerOrders(waiting, price, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	trailing, _ := repo.GetByID(ids[0])
+	if trailing.Type != domain.OrderTypeMarket || trailing.TriggeredFrom != domain.OrderTypeTrailingStop ||
+		trailing.StopPrice != 105 || trailing.Status != domain.TradeStatusFilled {
+		t.Errorf("expected the trailing stop to sell at market, got %+v", trailing)
+	}
+	takeProfit, _ := repo.GetByID(ids[1])
+	if takeProfit.Type != domain.OrderTypeTakeProfit || takeProfit.TriggeredAt != nil {
+		t.Errorf("expected the take profit to keep waiting, got %+v", takeProfit)
+	}
+	stopLimit, _ := repo.GetByID(ids[2])
+	if stopLimit.Type != domain.OrderTypeLimit || stopLimit.Status != domain.TradeStatusAccepted {
+		t.Errorf("expected the stop limit to rest as a limit order, got %+v", stopLimit)
+	}
+	if open, _ := repo.ListOpen(); len(open) != 2 {
+		t.Errorf("expected bob's bid and the released stop limit in the book, got %+v", open)
+	}
+}
+
+func TestFailedTriggerDoesNotBlockOthers(t *testing.T) {
+	repo := memory.NewTradeRepository(setupTestDB(t))
+	uc := newTestUsecase(t, repo, setupTestServices(t, 100))
+	ctx := context.Background()
+
+	stop, _, err := uc.PlaceTrade(ctx, "alice@example.com", domain.Trade{Symbol: "BTCUSD", Side: domain.SideBuy, Type: domain.OrderTypeStopLimit, Quantity: 1, StopPrice: 110, Price: 85})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An order that cannot be released comes first
+	waiting, _ := repo.ListConditional()
+	broken := waiting[0]
+	broken.ID = 9999
+	if err := uc.triggerOrders(append([]domain.Trade{broken}, waiting...), 110, time.Now()); err == nil {
+		t.Error("expected the failed release to be reported")
+	}
+	if released, _ := repo.GetByID(stop.ID); released.Type != domain.OrderTypeLimit || released.TriggeredAt == nil {
+		t.Errorf("expected the stop limit released anyway, got %+v", released)
+	}
+}